@@ -0,0 +1,186 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// ErrBufferFull возвращается при попытке добавить сообщение в заполненный буфер асинхронной публикации.
+var ErrBufferFull = errors.New("publishing buffer is full")
+
+// OverflowPolicy определяет поведение AsyncPublisher при заполненном буфере.
+type OverflowPolicy int
+
+const (
+	OverflowError OverflowPolicy = iota // возвращать ошибку ErrBufferFull
+	OverflowDrop                        // молча отбрасывать сообщение
+)
+
+// asyncMessage описывает сообщение в очереди на асинхронную публикацию.
+// Если задан flushed, то это не сообщение, а отметка для Flush.
+type asyncMessage struct {
+	exchange, key string
+	msg           amqp091.Publishing
+	flushed       chan error
+}
+
+// AsyncPublisher осуществляет асинхронную публикацию сообщений через внутренний буфер.
+// Сообщения добавляются в буфер с помощью Enqueue и публикуются в фоне через заданную функцию Publisher.
+// Ошибки публикации записываются в лог, а первая из них после предыдущего вызова Flush возвращается им.
+//
+// Если публикация выполняется с ожиданием подтверждений (WithConfirm), то накопленные в буфере сообщения
+// отправляются пакетом без ожидания подтверждения каждого, а затем подтверждения всего пакета ожидаются вместе.
+type AsyncPublisher struct {
+	publisher Publisher
+	policy    OverflowPolicy
+	queue     chan asyncMessage
+	ctx       context.Context
+}
+
+// NewAsyncPublisher возвращает инициализированный асинхронный публикатор с буфером заданного размера
+// и запускает фоновую публикацию сообщений. Публикация завершается при отмене контекста.
+func NewAsyncPublisher(ctx context.Context, pub Publisher, size int, policy OverflowPolicy) *AsyncPublisher {
+	p := &AsyncPublisher{
+		publisher: pub,
+		policy:    policy,
+		queue:     make(chan asyncMessage, size),
+		ctx:       ctx,
+	}
+	go p.run(ctx)
+	return p
+}
+
+// run публикует сообщения из буфера пакетами до отмены контекста.
+func (p *AsyncPublisher) run(ctx context.Context) {
+	var failed error // первая ошибка публикации после предыдущего Flush
+	for {
+		select {
+		case m := <-p.queue:
+			batch := []asyncMessage{m}
+		collect: // добавляем в пакет все уже накопленные сообщения
+			for len(batch) < cap(p.queue) {
+				select {
+				case m := <-p.queue:
+					batch = append(batch, m)
+				default:
+					break collect
+				}
+			}
+			failed = p.publishBatch(ctx, batch, failed)
+		case <-ctx.Done():
+			log.Debug().Int("dropped", len(p.queue)).Msg("async publisher stopped")
+			return
+		}
+	}
+}
+
+// asyncConfirm описывает ожидаемое подтверждение сообщения из пакета.
+type asyncConfirm struct {
+	key    string
+	result <-chan error
+}
+
+// publishBatch публикует пакет сообщений и возвращает первую ошибку публикации с учётом предыдущей failed.
+// Подтверждения сервера ожидаются после отправки всех сообщений пакета или перед отметкой Flush.
+func (p *AsyncPublisher) publishBatch(ctx context.Context, batch []asyncMessage, failed error) error {
+	var (
+		mu       sync.Mutex
+		confirms []asyncConfirm
+		key      string // ключ маршрутизации публикуемого сообщения
+	)
+	pubCtx := withConfirmSink(ctx, func(result <-chan error) {
+		mu.Lock()
+		confirms = append(confirms, asyncConfirm{key: key, result: result})
+		mu.Unlock()
+	})
+	fail := func(key string, err error) {
+		log.Err(err).Str("key", key).Msg("async publishing")
+		if failed == nil {
+			failed = err
+		}
+	}
+	// подтверждения приходят по порядку, поэтому фактически ожидается подтверждение последнего сообщения
+	wait := func() {
+		mu.Lock()
+		pending := confirms
+		confirms = nil
+		mu.Unlock()
+		for _, c := range pending {
+			select {
+			case err := <-c.result:
+				if err != nil {
+					fail(c.key, err)
+				}
+			case <-ctx.Done():
+				fail(c.key, ctx.Err())
+				return
+			}
+		}
+	}
+
+	for _, m := range batch {
+		if m.flushed != nil {
+			wait()
+			m.flushed <- failed // все сообщения до отметки уже опубликованы
+			failed = nil
+			continue
+		}
+		mu.Lock()
+		key = m.key
+		mu.Unlock()
+		if err := p.publisher(pubCtx, m.exchange, m.key, m.msg); err != nil {
+			fail(m.key, err)
+		}
+	}
+	wait()
+	return failed
+}
+
+// Enqueue добавляет сообщение в буфер для публикации и сразу возвращает управление.
+// Если буфер заполнен, то в зависимости от политики возвращается ошибка ErrBufferFull
+// или сообщение отбрасывается. После отмены контекста публикатора возвращается ошибка контекста.
+func (p *AsyncPublisher) Enqueue(exchange, key string, msg amqp091.Publishing) error {
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case p.queue <- asyncMessage{exchange: exchange, key: key, msg: msg}:
+		return nil
+	default:
+	}
+
+	if p.policy == OverflowDrop {
+		log.Warn().Str("key", key).Msg("async publishing buffer is full: message dropped")
+		return nil
+	}
+	return ErrBufferFull
+}
+
+// Flush ожидает публикации всех сообщений, добавленных в буфер до её вызова, и возвращает первую ошибку
+// их публикации (например, ErrNoChannel) после предыдущего вызова Flush. Возвращает ошибку контекста,
+// если контекст вызова или публикатора был отменён раньше.
+func (p *AsyncPublisher) Flush(ctx context.Context) error {
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+	flushed := make(chan error, 1)
+	select {
+	case p.queue <- asyncMessage{flushed: flushed}:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+
+	select {
+	case err := <-flushed:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
@@ -0,0 +1,79 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestAsyncPublisherConfirmBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := new(fakePublishChannel)
+	waiter := newConfirmWaiter()
+	confirms := make(chan amqp091.Confirmation)
+	go waiter.listen(confirms, nil)
+
+	// сообщения накапливаются в буфере до запуска публикации
+	release := make(chan struct{})
+	pub := channelPublisher(ch, publishOptions{confirm: true}, nil, waiter)
+	p := NewAsyncPublisher(ctx, func(ctx context.Context, exchange, key string, msg amqp091.Publishing) error {
+		<-release
+		return pub(ctx, exchange, key, msg)
+	}, 10, OverflowError)
+	for i := 0; i < 4; i++ {
+		if err := p.Enqueue("", "test", amqp091.Publishing{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	flushed := make(chan error, 1)
+	go func() { flushed <- p.Flush(ctx) }()
+	close(release)
+
+	// все сообщения пакета отправлены без ожидания подтверждений
+	for {
+		waiter.mu.Lock()
+		pending := len(waiter.pending)
+		waiter.mu.Unlock()
+		if pending == 4 {
+			break
+		}
+		runtime.Gosched()
+	}
+	confirms <- amqp091.Confirmation{DeliveryTag: 1, Ack: true}
+	confirms <- amqp091.Confirmation{DeliveryTag: 2, Ack: false}
+	confirms <- amqp091.Confirmation{DeliveryTag: 3, Ack: true}
+	confirms <- amqp091.Confirmation{DeliveryTag: 4, Ack: true}
+	if err := <-flushed; !errors.Is(err, ErrNacked) {
+		t.Errorf("flush: got %v, want ErrNacked", err)
+	}
+	if err := p.Flush(ctx); err != nil {
+		t.Errorf("second flush: %v", err)
+	}
+}
+
+func TestAsyncPublisherStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewAsyncPublisher(ctx, func(context.Context, string, string, amqp091.Publishing) error {
+		return ErrNoChannel
+	}, 10, OverflowError)
+
+	if err := p.Enqueue("", "test", amqp091.Publishing{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Flush(context.Background()); err != ErrNoChannel {
+		t.Errorf("flush without channel: got %v, want ErrNoChannel", err)
+	}
+
+	cancel()
+	if err := p.Enqueue("", "test", amqp091.Publishing{}); err != context.Canceled {
+		t.Errorf("enqueue after stop: got %v", err)
+	}
+	if err := p.Flush(context.Background()); err != context.Canceled {
+		t.Errorf("flush after stop: got %v", err)
+	}
+}
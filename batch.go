@@ -0,0 +1,102 @@
+package rabbitmq
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// BatchHandler является синонимом для функции обработки пакета входящих сообщений.
+type BatchHandler = func([]amqp091.Delivery) error
+
+// ErrInvalidBatch возвращается ConsumeBatch, если размер пакета или время его накопления не положительны.
+var ErrInvalidBatch = errors.New("batch size and wait time must be positive")
+
+// ConsumeBatch возвращает инициализированный обработчик входящих сообщений, который накапливает их в пакеты
+// и передаёт в функцию обработки. Пакет обрабатывается при достижении размера size или по истечении времени
+// maxWait с момента получения первого сообщения в пакете. При закрытии канала накопленные сообщения
+// обрабатываются как неполный пакет.
+//
+// При отключённом автоматическом подтверждении (WithNoAutoAck) весь пакет подтверждается после успешной
+// обработки или отклоняется, если функция обработки вернула ошибку. Возврат отклонённых сообщений в очередь
+// задаётся через SetDefaultNackBehavior.
+//
+// Если size или maxWait не положительны, то возвращается ошибка ErrInvalidBatch.
+func ConsumeBatch(queue *Queue, handler BatchHandler, size int, maxWait time.Duration,
+	opts ...ConsumeOption) (Initializer, error) {
+	if size <= 0 || maxWait <= 0 {
+		return nil, ErrInvalidBatch
+	}
+	options := getConsumeOptions(opts)
+	return consume(queue, options, batchWorker(queue, handler, size, maxWait, options)), nil
+}
+
+// batchWorker возвращает функцию, которая накапливает сообщения из канала в пакеты и передаёт их в функцию
+// обработки до закрытия канала.
+func batchWorker(queue *Queue, handler BatchHandler, size int, maxWait time.Duration,
+	options consumeOptions) func(<-chan amqp091.Delivery) {
+	log := log.With().Stringer("queue", queue).Logger()
+
+	return func(deliveries <-chan amqp091.Delivery) {
+		batch := make([]amqp091.Delivery, 0, size)
+		timer := time.NewTimer(maxWait)
+		stopTimer := func() {
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+		stopTimer()
+
+		// обрабатываем накопленный пакет сообщений
+		flush := func() {
+			if len(batch) == 0 {
+				return
+			}
+			err := handler(batch)
+			log.Debug().Err(err).Int("size", len(batch)).Msg("batch handled")
+			if options.noAutoAck {
+				// подтверждаем сразу все сообщения пакета по последнему из них
+				last := batch[len(batch)-1]
+				var ackErr error
+				if err == nil {
+					ackErr = last.Ack(true)
+				} else {
+					ackErr = last.Nack(true, nackRequeue)
+				}
+				if ackErr != nil {
+					log.Err(ackErr).Msg("batch acknowledgement")
+				}
+			}
+			batch = make([]amqp091.Delivery, 0, size)
+		}
+
+		for {
+			select {
+			case msg, ok := <-deliveries:
+				if !ok { // канал закрыт: обрабатываем неполный пакет
+					stopTimer()
+					flush()
+					return
+				}
+				logDelivery(msg)
+				if options.reject(msg) {
+					continue
+				}
+				batch = append(batch, msg)
+				if len(batch) == 1 {
+					timer.Reset(maxWait)
+				}
+				if len(batch) >= size {
+					stopTimer()
+					flush()
+				}
+			case <-timer.C:
+				flush()
+			}
+		}
+	}
+}
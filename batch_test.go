@@ -0,0 +1,135 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// batchAcker записывает подтверждения сообщений с их номером и признаком multiple.
+type batchAcker struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (a *batchAcker) record(format string, args ...interface{}) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls = append(a.calls, fmt.Sprintf(format, args...))
+	return nil
+}
+
+func (a *batchAcker) Ack(tag uint64, multiple bool) error {
+	return a.record("ack %d %v", tag, multiple)
+}
+func (a *batchAcker) Nack(tag uint64, multiple, _ bool) error {
+	return a.record("nack %d %v", tag, multiple)
+}
+func (a *batchAcker) Reject(tag uint64, _ bool) error { return a.record("reject %d", tag) }
+
+func (a *batchAcker) get() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.calls...)
+}
+
+// batchTags возвращает номера сообщений пакета.
+func batchTags(batch []amqp091.Delivery) []uint64 {
+	tags := make([]uint64, len(batch))
+	for i, msg := range batch {
+		tags[i] = msg.DeliveryTag
+	}
+	return tags
+}
+
+func TestConsumeBatchInvalid(t *testing.T) {
+	handler := func([]amqp091.Delivery) error { return nil }
+	for _, tc := range []struct {
+		size    int
+		maxWait time.Duration
+	}{{0, time.Second}, {-1, time.Second}, {10, 0}, {10, -time.Second}} {
+		init, err := ConsumeBatch(NewQueue("batch"), handler, tc.size, tc.maxWait)
+		if err != ErrInvalidBatch || init != nil {
+			t.Errorf("size %d, wait %v: got %v", tc.size, tc.maxWait, err)
+		}
+	}
+}
+
+func TestBatchWorker(t *testing.T) {
+	var (
+		acker      = new(batchAcker)
+		batches    = make(chan []uint64, 10)
+		errHandler = errors.New("handler")
+		handled    int
+	)
+	handler := func(batch []amqp091.Delivery) error {
+		batches <- batchTags(batch)
+		if handled++; handled > 1 {
+			return errHandler
+		}
+		return nil
+	}
+	options := getConsumeOptions([]ConsumeOption{WithNoAutoAck()})
+	worker := batchWorker(NewQueue("batch"), handler, 2, time.Hour, options)
+
+	deliveries := make(chan amqp091.Delivery)
+	done := make(chan struct{})
+	go func() {
+		worker(deliveries)
+		close(done)
+	}()
+	for tag := uint64(1); tag <= 3; tag++ {
+		deliveries <- amqp091.Delivery{Acknowledger: acker, DeliveryTag: tag}
+	}
+
+	// пакет обрабатывается при достижении размера, не дожидаясь времени накопления
+	if got := <-batches; fmt.Sprint(got) != "[1 2]" {
+		t.Errorf("size batch: %v", got)
+	}
+	// при закрытии канала обрабатывается неполный пакет
+	close(deliveries)
+	<-done
+	if got := <-batches; fmt.Sprint(got) != "[3]" {
+		t.Errorf("close batch: %v", got)
+	}
+
+	// пакет подтверждается или отклоняется одним вызовом по последнему сообщению
+	if got := acker.get(); fmt.Sprint(got) != "[ack 2 true nack 3 true]" {
+		t.Errorf("acknowledgements: %q", got)
+	}
+}
+
+func TestBatchWorkerTimeout(t *testing.T) {
+	batches := make(chan []uint64, 10)
+	handler := func(batch []amqp091.Delivery) error {
+		batches <- batchTags(batch)
+		return nil
+	}
+	worker := batchWorker(NewQueue("batch"), handler, 10, 10*time.Millisecond, getConsumeOptions(nil))
+
+	deliveries := make(chan amqp091.Delivery)
+	done := make(chan struct{})
+	go func() {
+		worker(deliveries)
+		close(done)
+	}()
+	defer func() {
+		close(deliveries)
+		<-done
+	}()
+
+	deliveries <- amqp091.Delivery{DeliveryTag: 1}
+	// неполный пакет обрабатывается по истечении времени накопления
+	select {
+	case got := <-batches:
+		if fmt.Sprint(got) != "[1]" {
+			t.Errorf("timeout batch: %v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("batch not flushed by timeout")
+	}
+}
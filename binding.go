@@ -0,0 +1,149 @@
+package rabbitmq
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Binding описывает привязку очереди к точке обмена.
+type Binding struct {
+	Exchange string        // название точки обмена
+	Key      string        // ключ маршрутизации
+	Args     amqp091.Table // дополнительные параметры
+}
+
+// BindError описывает ошибку одной привязки очереди.
+type BindError struct {
+	Binding Binding // привязка
+	Err     error   // ошибка привязки
+}
+
+func (e *BindError) Error() string {
+	return "bind " + e.Binding.Exchange + " (" + e.Binding.Key + "): " + e.Err.Error()
+}
+func (e *BindError) Unwrap() error { return e.Err }
+
+// BindErrors описывает ошибки привязок при вызове BindAll.
+type BindErrors []*BindError
+
+func (e BindErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap возвращает ошибки отдельных привязок.
+func (e BindErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Is и As позволяют проверять ошибки отдельных привязок через errors.Is и errors.As и в версиях Go,
+// которые не поддерживают Unwrap со списком ошибок.
+func (e BindErrors) Is(target error) bool { return errorsIs(e.Unwrap(), target) }
+func (e BindErrors) As(target any) bool   { return errorsAs(e.Unwrap(), target) }
+
+// BindAll привязывает уже задекларированную очередь к нескольким точкам обмена. Ошибка одной привязки
+// не прерывает выполнение остальных: если какие-то привязки не удались, то возвращается ошибка BindErrors.
+// Следует учитывать, что ошибка сервера закрывает канал, поэтому все последующие привязки тоже завершатся ошибкой.
+//
+// Привязки сохраняются на сервере только вместе с очередью, поэтому для временных очередей их следует
+// повторять при каждом подключении, например, через опцию WithPreStart:
+//
+//	queue.Consume(handler, rabbitmq.WithPreStart(func(ch *amqp091.Channel) error {
+//		return queue.BindAll(ch, bindings)
+//	}))
+func (q *Queue) BindAll(ch *amqp091.Channel, bindings []Binding) error {
+	var errs BindErrors
+	for _, b := range bindings {
+		err := ch.QueueBind(q.String(), b.Key, b.Exchange, false, b.Args)
+		log.Debug().Err(err).Stringer("queue", q).Str("exchange", b.Exchange).Str("key", b.Key).Msg("queue bind")
+		if err != nil {
+			errs = append(errs, &BindError{Binding: b, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// BindIfNeeded привязывает уже задекларированную очередь к нескольким точкам обмена, предварительно проверяя
+// их наличие пассивной декларацией. Привязки к отсутствующим точкам обмена (как и привязки уже удалённой
+// очереди) пропускаются с записью в лог, а не приводят к ошибке, поэтому восстановление топологии при
+// переподключении не прерывается из-за временной несогласованности, например, пока точка обмена создаётся
+// заново. Повторная привязка уже существующей связи в AMQP ошибкой не является.
+//
+// Ошибка сервера закрывает канал, поэтому проверки и привязки выполняются на отдельном канале того же
+// соединения, а канал ch остаётся доступен. Для этого канал должен быть инициализирован в Run (например,
+// при вызове из WithPreStart); в остальных случаях BindIfNeeded работает как BindAll. Остальные ошибки
+// привязок возвращаются как BindErrors.
+func (q *Queue) BindIfNeeded(ch *amqp091.Channel, bindings []Binding) error {
+	conn := channelConnection(ch)
+	if conn == nil {
+		return q.BindAll(ch, bindings)
+	}
+	return q.bindIfNeeded(func() (bindChannel, error) { return conn.Channel() }, bindings)
+}
+
+// bindChannel описывает методы канала, используемые для проверки и выполнения привязок.
+type bindChannel interface {
+	ExchangeDeclarePassive(name, kind string, durable, autoDelete, internal, noWait bool, args amqp091.Table) error
+	QueueBind(name, key, exchange string, noWait bool, args amqp091.Table) error
+	Close() error
+}
+
+// bindIfNeeded выполняет привязки BindIfNeeded на каналах, открываемых функцией open. После ошибки сервера
+// канал закрыт, поэтому для следующей привязки открывается новый.
+func (q *Queue) bindIfNeeded(open func() (bindChannel, error), bindings []Binding) error {
+	var (
+		errs BindErrors
+		ch   bindChannel
+	)
+	defer func() {
+		if ch != nil {
+			ch.Close()
+		}
+	}()
+
+	for _, b := range bindings {
+		if ch == nil {
+			var err error
+			if ch, err = open(); err != nil {
+				return err
+			}
+		}
+
+		log := log.With().Stringer("queue", q).Str("exchange", b.Exchange).Str("key", b.Key).Logger()
+		// тип точки обмена при пассивной декларации не проверяется
+		err := ch.ExchangeDeclarePassive(b.Exchange, amqp091.ExchangeDirect, false, false, false, false, nil)
+		if err == nil {
+			err = ch.QueueBind(q.String(), b.Key, b.Exchange, false, b.Args)
+		}
+		if err == nil {
+			log.Debug().Msg("queue bind")
+			continue
+		}
+
+		ch.Close() // канал уже закрыт сервером
+		ch = nil
+		var amqpErr *amqp091.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp091.NotFound {
+			log.Warn().Str("reason", amqpErr.Reason).Msg("queue bind skipped")
+			continue
+		}
+		log.Err(err).Msg("queue bind")
+		errs = append(errs, &BindError{Binding: b, Err: err})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
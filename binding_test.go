@@ -0,0 +1,96 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestBindErrors(t *testing.T) {
+	var err error = BindErrors{
+		{Binding: Binding{Exchange: "a"}, Err: errors.New("other")},
+		{Binding: Binding{Exchange: "b"}, Err: amqp091.ErrClosed},
+	}
+	if !errors.Is(err, amqp091.ErrClosed) {
+		t.Errorf("errors.Is: %v does not match ErrClosed", err)
+	}
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) || bindErr.Binding.Exchange != "a" {
+		t.Errorf("errors.As: got %v", bindErr)
+	}
+	var amqpErr *amqp091.Error
+	if !errors.As(err, &amqpErr) || amqpErr.Code != amqp091.ChannelError {
+		t.Errorf("errors.As: got %v", amqpErr)
+	}
+}
+
+// fakeBindChannel имитирует канал, на котором известны только заданные точки обмена.
+type fakeBindChannel struct {
+	exchanges map[string]bool
+	bindErr   error
+	bound     *[]string
+	closed    bool
+}
+
+func (c *fakeBindChannel) ExchangeDeclarePassive(name, _ string, _, _, _, _ bool, _ amqp091.Table) error {
+	if c.closed {
+		return amqp091.ErrClosed
+	}
+	if !c.exchanges[name] {
+		c.closed = true
+		return &amqp091.Error{Code: amqp091.NotFound, Reason: "NOT_FOUND - no exchange '" + name + "'"}
+	}
+	return nil
+}
+
+func (c *fakeBindChannel) QueueBind(_, key, exchange string, _ bool, _ amqp091.Table) error {
+	if c.closed {
+		return amqp091.ErrClosed
+	}
+	if c.bindErr != nil && key == "fail" {
+		c.closed = true
+		return c.bindErr
+	}
+	*c.bound = append(*c.bound, exchange+":"+key)
+	return nil
+}
+
+func (c *fakeBindChannel) Close() error { c.closed = true; return nil }
+
+func TestBindIfNeeded(t *testing.T) {
+	var (
+		bound   []string
+		opened  int
+		errBind = &amqp091.Error{Code: amqp091.AccessRefused}
+	)
+	open := func() (bindChannel, error) {
+		opened++
+		return &fakeBindChannel{
+			exchanges: map[string]bool{"events": true, "audit": true},
+			bindErr:   errBind,
+			bound:     &bound,
+		}, nil
+	}
+
+	queue := NewQueue("tasks")
+	err := queue.bindIfNeeded(open, []Binding{
+		{Exchange: "events", Key: "a"},
+		{Exchange: "missing", Key: "b"}, // пропускается, канал закрывается сервером
+		{Exchange: "audit", Key: "c"},
+		{Exchange: "audit", Key: "fail"},
+		{Exchange: "events", Key: "d"},
+	})
+
+	if got := fmt.Sprint(bound); got != "[events:a audit:c events:d]" {
+		t.Errorf("bound: %s", got)
+	}
+	if opened != 3 {
+		t.Errorf("opened %d channels, want 3", opened)
+	}
+	var errs BindErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Binding.Key != "fail" || !errors.Is(err, errBind) {
+		t.Errorf("got %v, want only the failed binding", err)
+	}
+}
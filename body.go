@@ -0,0 +1,59 @@
+package rabbitmq
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// ErrUnknownEncoding возвращается Body, если кодировка содержимого сообщения не поддерживается.
+var ErrUnknownEncoding = errors.New("unknown content encoding")
+
+// ErrBodyTooLarge возвращается Body, если размер распакованного тела сообщения превышает MaxBodySize.
+var ErrBodyTooLarge = errors.New("decoded message body is too large")
+
+// MaxBodySize ограничивает размер тела сообщения после распаковки в Body, чтобы небольшое сжатое сообщение
+// не могло занять всю память. По умолчанию 64 МБ.
+//
+// Не является потокобезопасным значением и рекомендуется переопределять перед началом работы с библиотекой.
+var MaxBodySize int64 = 64 << 20
+
+// Body возвращает тело сообщения, распакованное в соответствии с его кодировкой (ContentEncoding).
+// Поддерживаются кодировки gzip и deflate (в формате zlib). Сообщения без кодировки или с кодировкой
+// identity возвращаются без изменений. Для остальных кодировок возвращается ошибка ErrUnknownEncoding,
+// а если распакованное тело больше MaxBodySize — ErrBodyTooLarge.
+func Body(d amqp091.Delivery) ([]byte, error) {
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	switch encoding := strings.ToLower(strings.TrimSpace(d.ContentEncoding)); encoding {
+	case "", "identity":
+		return d.Body, nil
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(d.Body))
+	case "deflate":
+		r, err = zlib.NewReader(bytes.NewReader(d.Body))
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEncoding, encoding)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	body, err := io.ReadAll(io.LimitReader(r, MaxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > MaxBodySize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, MaxBodySize)
+	}
+	return body, nil
+}
@@ -0,0 +1,69 @@
+package rabbitmq
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestBody(t *testing.T) {
+	data := []byte("hello, world")
+
+	var gz, zl bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(data)
+	w.Close()
+	z := zlib.NewWriter(&zl)
+	z.Write(data)
+	z.Close()
+
+	for _, test := range []struct {
+		encoding string
+		body     []byte
+	}{
+		{"", data},
+		{"identity", data},
+		{"gzip", gz.Bytes()},
+		{"deflate", zl.Bytes()},
+	} {
+		body, err := Body(amqp091.Delivery{ContentEncoding: test.encoding, Body: test.body})
+		if err != nil {
+			t.Errorf("%q: %v", test.encoding, err)
+		} else if !bytes.Equal(body, data) {
+			t.Errorf("%q: got %q", test.encoding, body)
+		}
+	}
+
+	if _, err := Body(amqp091.Delivery{ContentEncoding: "br", Body: data}); !errors.Is(err, ErrUnknownEncoding) {
+		t.Errorf("unknown encoding: got %v", err)
+	}
+}
+
+func TestBodyTooLarge(t *testing.T) {
+	saved := MaxBodySize
+	MaxBodySize = 1 << 10
+	defer func() { MaxBodySize = saved }()
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(make([]byte, 1<<20)) // сжимается до нескольких килобайт
+	w.Close()
+
+	_, err := Body(amqp091.Delivery{ContentEncoding: "gzip", Body: gz.Bytes()})
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("got %v, want ErrBodyTooLarge", err)
+	}
+
+	gz.Reset()
+	w = gzip.NewWriter(&gz)
+	w.Write(make([]byte, MaxBodySize))
+	w.Close()
+	if body, err := Body(amqp091.Delivery{ContentEncoding: "gzip", Body: gz.Bytes()}); err != nil ||
+		int64(len(body)) != MaxBodySize {
+		t.Errorf("body at limit: %d bytes, %v", len(body), err)
+	}
+}
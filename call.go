@@ -0,0 +1,90 @@
+package rabbitmq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Call выполняет одиночный запрос с ожиданием ответа: подключается к серверу, создаёт временную приватную
+// очередь для ответа, публикует сообщение с заполненными полями ReplyTo и CorrelationId, ожидает ответ
+// с тем же CorrelationId и закрывает соединение.
+//
+// Если CorrelationId в сообщении не задан, то он генерируется автоматически. Ограничение времени всего запроса
+// задаётся через контекст. Функция предназначена для скриптов и тестов: для постоянного обмена сообщениями
+// используйте Run с инициализаторами.
+func Call(ctx context.Context, addr, exchange, key string, msg amqp091.Publishing) (amqp091.Delivery, error) {
+	conn, err := connect(ctx, addr)
+	if err != nil {
+		return amqp091.Delivery{}, err
+	}
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return amqp091.Delivery{}, err
+	}
+
+	// создаём очередь для ответа и начинаем получать из неё сообщения
+	queue := NewPrivateQueue()
+	replies, _, err := startConsume(ch, queue, consumeOptions{anonymous: true})
+	if err != nil {
+		return amqp091.Delivery{}, err
+	}
+
+	if msg.CorrelationId == "" {
+		if msg.CorrelationId, err = newCorrelationID(); err != nil {
+			return amqp091.Delivery{}, err
+		}
+	}
+	msg.ReplyTo = queue.String()
+
+	publisher := Publisher(func(ctx context.Context, exchange, key string, msg amqp091.Publishing) error {
+		return ch.PublishWithContext(ctx, exchange, key, false, false, msg)
+	})
+	if err := publishWithContext(ctx, publisher, exchange, key, msg); err != nil {
+		return amqp091.Delivery{}, err
+	}
+	log.Debug().Str("key", key).Str("correlationId", msg.CorrelationId).Msg("call")
+
+	// ожидаем ответ на наш запрос, пропуская остальные сообщения
+	for {
+		select {
+		case reply, ok := <-replies:
+			if !ok {
+				return amqp091.Delivery{}, amqp091.ErrClosed
+			}
+			if reply.CorrelationId == msg.CorrelationId {
+				return reply, nil
+			}
+		case <-ctx.Done():
+			return amqp091.Delivery{}, ctx.Err()
+		}
+	}
+}
+
+// PublishWithReplies возвращает функцию публикации и инициализатор, который на одном общем канале запускает
+// получение ответов из очереди queue и публикацию запросов. При переподключении оба инициализируются заново
+// вместе. Это экономит каналы соединения для клиентов RPC и гарантирует порядок операций в рамках канала.
+//
+// Очередь для ответов декларируется до инициализации публикации, поэтому её имя (в том числе сгенерированное
+// сервером) уже известно и автоматически подставляется в поле ReplyTo отправляемых сообщений.
+// В качестве опций используются PublishOption и ConsumeOption.
+func PublishWithReplies(queue *Queue, handler Handler, opts ...WorkOption) (Publisher, Initializer) {
+	options := getWorkOptions(opts)
+	pubOpts := append([]PublishOption{WithReplyToQueue(queue)}, options.publish...)
+	publisher, pubInit := Publish(pubOpts...)
+	consumeInit := queue.Consume(handler, options.consume...)
+	return publisher, Chain(consumeInit, pubInit)
+}
+
+// newCorrelationID возвращает случайный идентификатор для сопоставления запроса и ответа.
+func newCorrelationID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id[:]), nil
+}
@@ -0,0 +1,195 @@
+package rabbitmq
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// confirmTracker отслеживает подтверждения опубликованных в канал сообщений и передаёт их в функцию обработки.
+// При закрытии канала все неподтверждённые сообщения считаются отклонёнными.
+type confirmTracker struct {
+	callback  func(tag uint64, ack bool)
+	mu        sync.Mutex
+	published uint64 // номер последнего опубликованного сообщения
+	confirmed uint64 // номер последнего подтверждённого сообщения
+	closed    bool   // канал закрыт
+}
+
+// publish регистрирует номер опубликованного сообщения.
+func (t *confirmTracker) publish(tag uint64) {
+	t.mu.Lock()
+	if tag > t.published {
+		t.published = tag
+	}
+	// канал уже закрыт, а сообщение не попало в список отклонённых
+	rejected := t.closed && tag > t.confirmed
+	t.mu.Unlock()
+
+	if rejected {
+		t.callback(tag, false)
+	}
+}
+
+// listen получает подтверждения до закрытия канала. Библиотека amqp091-go передаёт подтверждения строго
+// в порядке номеров сообщений, разворачивая групповые подтверждения в отдельные.
+func (t *confirmTracker) listen(confirms <-chan amqp091.Confirmation) {
+	for confirm := range confirms {
+		t.mu.Lock()
+		t.confirmed = confirm.DeliveryTag
+		t.mu.Unlock()
+		t.callback(confirm.DeliveryTag, confirm.Ack)
+	}
+
+	// канал закрыт: все неподтверждённые сообщения считаем отклонёнными
+	t.mu.Lock()
+	from, to := t.confirmed, t.published
+	if to > from {
+		t.confirmed = to
+	}
+	t.closed = true
+	t.mu.Unlock()
+
+	for tag := from + 1; tag <= to; tag++ {
+		t.callback(tag, false)
+	}
+	log.Debug().Msg("publishing confirms closed")
+}
+
+// ErrUnroutable возвращается при публикации с опциями WithMandatory и WithConfirm, если сервер не смог
+// доставить сообщение ни в одну очередь и вернул его отправителю.
+var ErrUnroutable = errors.New("message is unroutable")
+
+// ErrNacked возвращается при публикации с опцией WithConfirm, если сервер отказался принять сообщение.
+var ErrNacked = errors.New("message was rejected by server")
+
+// pendingConfirm описывает опубликованное сообщение, ожидающее подтверждения сервера.
+type pendingConfirm struct {
+	exchange, key string
+	returned      bool       // сообщение было возвращено сервером как не доставленное
+	result        chan error // результат публикации
+}
+
+// confirmWaiter позволяет синхронно дождаться подтверждения публикации сообщения.
+//
+// Для публикаций с флагом mandatory сервер сначала возвращает недоставленное сообщение (basic.return)
+// и только затем подтверждает его приём. Возвраты и подтверждения обрабатываются в одной горутине,
+// поэтому к моменту подтверждения признак возврата сообщения уже известен.
+type confirmWaiter struct {
+	mu      sync.Mutex
+	pending map[uint64]*pendingConfirm
+	order   []uint64 // номера ожидающих сообщений по возрастанию, в том числе уже удалённых из pending
+	closed  bool     // канал закрыт
+}
+
+func newConfirmWaiter() *confirmWaiter {
+	return &confirmWaiter{pending: make(map[uint64]*pendingConfirm)}
+}
+
+// add регистрирует сообщение с указанным номером до его публикации и возвращает канал для получения результата.
+func (w *confirmWaiter) add(tag uint64, exchange, key string) <-chan error {
+	result := make(chan error, 1)
+	w.mu.Lock()
+	if w.closed {
+		result <- ErrChannelClosed
+	} else {
+		w.pending[tag] = &pendingConfirm{exchange: exchange, key: key, result: result}
+		// номера сообщений возрастают, но после неудачной публикации номер может повториться
+		if n := len(w.order); n == 0 || w.order[n-1] < tag {
+			w.order = append(w.order, tag)
+		} else if i := sort.Search(n, func(i int) bool { return w.order[i] >= tag }); w.order[i] != tag {
+			w.order = append(w.order[:i+1], w.order[i:]...)
+			w.order[i] = tag
+		}
+	}
+	w.mu.Unlock()
+	return result
+}
+
+// remove удаляет регистрацию сообщения, которое не удалось опубликовать.
+func (w *confirmWaiter) remove(tag uint64) {
+	w.mu.Lock()
+	delete(w.pending, tag)
+	w.mu.Unlock()
+}
+
+// listen обрабатывает возвраты и подтверждения сообщений до закрытия канала.
+// Канал возвратов может быть nil, если публикация осуществляется без флага mandatory.
+func (w *confirmWaiter) listen(confirms <-chan amqp091.Confirmation, returns <-chan amqp091.Return) {
+	for {
+		select {
+		case r, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			w.returned(r)
+		case confirm, ok := <-confirms:
+			if !ok {
+				w.close()
+				return
+			}
+			w.confirm(confirm)
+		}
+	}
+}
+
+// returned отмечает возвращённое сервером сообщение. Возврат не содержит номера сообщения, поэтому
+// выбирается самое раннее из ожидающих подтверждения сообщений с той же точкой обмена и ключом маршрутизации.
+func (w *confirmWaiter) returned(r amqp091.Return) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, tag := range w.order {
+		if p, ok := w.pending[tag]; ok && !p.returned && p.exchange == r.Exchange && p.key == r.RoutingKey {
+			p.returned = true
+			break
+		}
+	}
+	log.Debug().Str("key", r.RoutingKey).Str("reason", r.ReplyText).Msg("message returned")
+}
+
+// confirm передаёт результат публикации подтверждённых сообщений.
+//
+// Библиотека amqp091-go разворачивает подтверждение сразу нескольких сообщений (multiple) в отдельные
+// подтверждения и передаёт их строго по порядку номеров. Поэтому все ожидающие сообщения с номером не больше
+// указанного считаются подтверждёнными одновременно с ним и снимаются с начала очереди номеров: обработка
+// каждого подтверждения не зависит от количества ожидающих сообщений.
+func (w *confirmWaiter) confirm(confirm amqp091.Confirmation) {
+	w.mu.Lock()
+	confirmed := make([]*pendingConfirm, 0, 1)
+	for len(w.order) > 0 && w.order[0] <= confirm.DeliveryTag {
+		tag := w.order[0]
+		w.order = w.order[1:]
+		if p, ok := w.pending[tag]; ok {
+			confirmed = append(confirmed, p)
+			delete(w.pending, tag)
+		}
+	}
+	w.mu.Unlock()
+
+	for _, p := range confirmed {
+		switch {
+		case !confirm.Ack:
+			p.result <- ErrNacked
+		case p.returned:
+			p.result <- ErrUnroutable
+		default:
+			p.result <- nil
+		}
+	}
+}
+
+// close завершает ожидание всех неподтверждённых сообщений с ошибкой закрытия канала.
+func (w *confirmWaiter) close() {
+	w.mu.Lock()
+	w.closed = true
+	for tag, p := range w.pending {
+		p.result <- ErrChannelClosed
+		delete(w.pending, tag)
+	}
+	w.order = nil
+	w.mu.Unlock()
+}
@@ -0,0 +1,146 @@
+package rabbitmq
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestConfirmWaiterUnroutable(t *testing.T) {
+	w := newConfirmWaiter()
+	first := w.add(1, "ex", "key")
+	second := w.add(2, "ex", "key")
+	third := w.add(3, "ex", "other")
+	fourth := w.add(4, "ex", "other")
+
+	confirms := make(chan amqp091.Confirmation)
+	returns := make(chan amqp091.Return)
+	done := make(chan struct{})
+	go func() {
+		w.listen(confirms, returns)
+		close(done)
+	}()
+
+	returns <- amqp091.Return{Exchange: "ex", RoutingKey: "key"} // возврат приходит до подтверждения
+	confirms <- amqp091.Confirmation{DeliveryTag: 1, Ack: true}
+	confirms <- amqp091.Confirmation{DeliveryTag: 2, Ack: true}
+	confirms <- amqp091.Confirmation{DeliveryTag: 3, Ack: false}
+	close(returns)
+	close(confirms)
+	<-done
+
+	for i, test := range []struct {
+		result <-chan error
+		want   error
+	}{
+		{first, ErrUnroutable},
+		{second, nil},
+		{third, ErrNacked},
+		{fourth, ErrChannelClosed},
+	} {
+		if err := <-test.result; !errors.Is(err, test.want) {
+			t.Errorf("message %d: got %v, want %v", i+1, err, test.want)
+		}
+	}
+
+	if err := <-w.add(5, "ex", "key"); !errors.Is(err, ErrChannelClosed) {
+		t.Errorf("after close: got %v", err)
+	}
+}
+
+func TestConfirmWaiterConcurrent(t *testing.T) {
+	const publishers, messages = 16, 200
+
+	w := newConfirmWaiter()
+	confirms := make(chan amqp091.Confirmation)
+	go w.listen(confirms, nil)
+
+	// имитация сервера: подтверждает опубликованные сообщения диапазонами (multiple)
+	var (
+		mu        sync.Mutex
+		next      uint64 = 1 // номер следующего публикуемого сообщения
+		published        = make(chan uint64, publishers*messages)
+	)
+	go func() {
+		for tag := range published {
+			if len(published) > 0 && tag%5 != 0 {
+				continue // откладываем подтверждение, чтобы следующее подтвердило диапазон
+			}
+			confirms <- amqp091.Confirmation{DeliveryTag: tag, Ack: tag%7 != 0}
+		}
+		close(confirms)
+	}()
+
+	var wg sync.WaitGroup
+	var failed, nacked int32
+	for i := 0; i < publishers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < messages; j++ {
+				mu.Lock() // номер сообщения определяется и регистрируется вместе с публикацией
+				tag := next
+				next++
+				result := w.add(tag, "", "test")
+				published <- tag
+				mu.Unlock()
+
+				switch err := <-result; {
+				case errors.Is(err, ErrNacked):
+					atomic.AddInt32(&nacked, 1)
+				case err != nil:
+					atomic.AddInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(published)
+
+	if failed != 0 {
+		t.Errorf("%d publishings failed", failed)
+	}
+	if nacked == 0 {
+		t.Error("no nacks received")
+	}
+}
+
+func TestConfirmWaiterOrder(t *testing.T) {
+	w := newConfirmWaiter()
+	first := w.add(1, "", "test")
+	w.add(2, "", "test")
+	w.remove(2) // публикация не удалась, и номер используется повторно
+	second := w.add(2, "", "test")
+	third := w.add(3, "", "test")
+
+	w.confirm(amqp091.Confirmation{DeliveryTag: 2, Ack: true})
+	for i, result := range []<-chan error{first, second} {
+		select {
+		case err := <-result:
+			if err != nil {
+				t.Errorf("message %d: %v", i+1, err)
+			}
+		default:
+			t.Errorf("message %d not confirmed", i+1)
+		}
+	}
+	select {
+	case err := <-third:
+		t.Errorf("message 3 resolved early: %v", err)
+	default:
+	}
+	if len(w.order) != 1 || len(w.pending) != 1 {
+		t.Errorf("pending %d, order %v", len(w.pending), w.order)
+	}
+
+	w.confirm(amqp091.Confirmation{DeliveryTag: 3, Ack: false})
+	if err := <-third; !errors.Is(err, ErrNacked) {
+		t.Errorf("message 3: got %v, want ErrNacked", err)
+	}
+	if len(w.order) != 0 || len(w.pending) != 0 {
+		t.Errorf("waiter not empty: pending %d, order %v", len(w.pending), w.order)
+	}
+}
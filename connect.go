@@ -1,6 +1,8 @@
 package rabbitmq
 
 import (
+	"context"
+	"net"
 	"time"
 
 	"github.com/rabbitmq/amqp091-go"
@@ -9,22 +11,99 @@ import (
 // Параметры для переподключения к серверу RabbitMQ в случае ошибки.
 var (
 	ReconnectDelay = time.Second * 2 // задержка перед повторным соединением
-	MaxIteration   = 5               // максимальное количество попыток
+	MaxIteration   = 5               // максимальное количество попыток (отрицательное — без ограничений)
 )
 
 // Connect возвращает инициализированное подключение к серверу RabbitMQ.
 //
 // В случае ошибки подключения попытка повторяется несколько раз с небольшой задержкой
-// (смотри MaxIteration и ReconnectTime).
-func Connect(addr string) (conn *amqp091.Connection, err error) {
-	for i := 0; i < MaxIteration; i++ {
-		conn, err = amqp091.Dial(addr) // подключаемся к серверу
+// (смотри MaxIteration и ReconnectTime). Если MaxIteration отрицательное, то попытки подключения
+// повторяются до успешного соединения.
+//
+// Опции подключения применяются после заданных через SetConnectOptions.
+func Connect(addr string, opts ...ConnectOption) (conn *amqp091.Connection, err error) {
+	return connect(context.Background(), addr, opts...)
+}
+
+// connect подключается к серверу RabbitMQ с повторами попыток соединения, пока не будет отменён контекст.
+func connect(ctx context.Context, addr string, opts ...ConnectOption) (conn *amqp091.Connection, err error) {
+	config := getConnectConfig(opts)
+	for i := 0; MaxIteration < 0 || i < MaxIteration; i++ {
+		conn, err = amqp091.DialConfig(addr, config) // подключаемся к серверу
 		log.Debug().Err(err).Msg("connection")
 		if err == nil {
 			return conn, nil // в случае успешного подключения сразу возвращаем его
 		}
-		time.Sleep(ReconnectDelay) // задержка перед повтором попытки соединения
+		if MaxIteration < 0 {
+			log.Warn().Err(err).Int("attempt", i+1).Msg("connection failed")
+		}
+
+		// задержка перед повтором попытки соединения
+		select {
+		case <-time.After(ReconnectDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 	// все попытки подключения исчерпаны
 	return nil, err
 }
+
+// ServerProperties возвращает свойства сервера, полученные при установке соединения: название продукта,
+// версию, платформу и поддерживаемые возможности.
+func ServerProperties(conn *amqp091.Connection) amqp091.Table {
+	return conn.Properties
+}
+
+// HasCapability возвращает true, если сервер сообщил о поддержке указанной возможности,
+// например "publisher_confirms" или "consumer_priorities". Позволяет на старте убедиться,
+// что сервер поддерживает всё необходимое.
+func HasCapability(conn *amqp091.Connection, name string) bool {
+	capabilities, _ := conn.Properties["capabilities"].(amqp091.Table)
+	supported, _ := capabilities[name].(bool)
+	return supported
+}
+
+// defaultConnectOptions задают опции подключения, используемые по умолчанию.
+var defaultConnectOptions []ConnectOption
+
+// SetConnectOptions задаёт опции подключения по умолчанию, в том числе для Run, Init и Work.
+// Не является потокобезопасным методом и рекомендуется переопределять перед началом работы с библиотекой.
+func SetConnectOptions(opts ...ConnectOption) {
+	defaultConnectOptions = opts
+}
+
+// getConnectConfig возвращает настройки подключения после применения всех изменений.
+// Значения по умолчанию совпадают с используемыми в amqp091.Dial.
+func getConnectConfig(opts []ConnectOption) amqp091.Config {
+	config := amqp091.Config{
+		Heartbeat: 10 * time.Second,
+		Locale:    "en_US",
+		Dial:      amqp091.DefaultDial(30 * time.Second),
+	}
+	for _, opt := range defaultConnectOptions {
+		opt.apply(&config)
+	}
+	for _, opt := range opts {
+		opt.apply(&config)
+	}
+	return config
+}
+
+// ConnectOption изменяет настройки подключения к серверу.
+type ConnectOption interface{ apply(*amqp091.Config) }
+
+type funcConnectOption struct{ f func(*amqp091.Config) }
+
+func (fco *funcConnectOption) apply(c *amqp091.Config) { fco.f(c) }
+
+func newFuncConnectOption(f func(*amqp091.Config)) *funcConnectOption {
+	return &funcConnectOption{f: f}
+}
+
+// WithDialFunc задаёт функцию для установки сетевого соединения с сервером. Это позволяет подключаться через
+// прокси, использовать собственное разрешение имён или отслеживать соединения.
+// По умолчанию используется amqp091.DefaultDial с таймаутом 30 секунд.
+func WithDialFunc(v func(network, addr string) (net.Conn, error)) ConnectOption {
+	return newFuncConnectOption(func(c *amqp091.Config) { c.Dial = v })
+}
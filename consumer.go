@@ -1,50 +1,152 @@
 package rabbitmq
 
 import (
+	"context"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
 	"github.com/rabbitmq/amqp091-go"
 )
 
 // Handler является синонимом для функции обработки входящих сообщений.
 type Handler = func(amqp091.Delivery)
 
+// CtxHandler является синонимом для функции обработки входящих сообщений с поддержкой контекста.
+type CtxHandler = func(context.Context, amqp091.Delivery)
+
+// deliveryKey используется как ключ для хранения входящего сообщения в контексте.
+type deliveryKey struct{}
+
+// DeliveryFromContext возвращает входящее сообщение, сохранённое в контексте его обработки.
+// Контекст с сообщением передаётся в обработчики, инициализированные через ConsumeCtx.
+func DeliveryFromContext(ctx context.Context) (amqp091.Delivery, bool) {
+	msg, ok := ctx.Value(deliveryKey{}).(amqp091.Delivery)
+	return msg, ok
+}
+
 // Consume возвращает инициализированный обработчик входящих сообщений для указанной очереди.
 //
 // По умолчанию включено автоматическое подтверждение приёма сообщения.
 // Для его отключения используйте опцию WithNoAutoAck().
 func Consume(queue *Queue, handler Handler, opts ...ConsumeOption) Initializer {
+	ctxHandler := func(_ context.Context, msg amqp091.Delivery) { handler(msg) }
+	return ConsumeCtx(context.Background(), queue, ctxHandler, opts...)
+}
+
+// ConsumeCtx возвращает инициализированный обработчик входящих сообщений для указанной очереди, который
+// передаёт в функцию обработки контекст. Для каждого сообщения создаётся отдельный дочерний контекст,
+// который отменяется после завершения его обработки. Само сообщение можно получить из этого контекста
+// с помощью DeliveryFromContext.
+//
+// Обычно в качестве контекста используется тот же, что передаётся в Run, чтобы обработчики могли
+// отслеживать плановое завершение работы сервиса.
+func ConsumeCtx(ctx context.Context, queue *Queue, handler CtxHandler, opts ...ConsumeOption) Initializer {
+	options := getConsumeOptions(opts)
+	return consume(queue, options, handleDeliveries(ctx, options, handler))
+}
+
+// handleDeliveries возвращает функцию, которая получает сообщения из канала и вызывает для них обработчик
+// с учётом настроек.
+func handleDeliveries(ctx context.Context, options consumeOptions, handler CtxHandler) func(<-chan amqp091.Delivery) {
+	return func(deliveries <-chan amqp091.Delivery) {
+		var inflight *inflightTracker
+		if options.inflightLog > 0 && options.noAutoAck {
+			inflight = newInflightTracker(options.qos)
+			defer inflight.report(options.inflightLog)()
+		}
+
+		options.dispatch(deliveries, func(msg amqp091.Delivery) {
+			if inflight != nil {
+				inflight.track(&msg)
+			}
+			logDelivery(msg)
+			if options.reject(msg) {
+				return
+			}
+			msgCtx, cancel := context.WithCancel(context.WithValue(ctx, deliveryKey{}, msg))
+			defer cancel()
+			defer watchHandler(msg, options.heartbeatLog)() // останавливается и при панике обработчика
+			handler(msgCtx, msg)
+		})
+	}
+}
+
+// logDelivery записывает в лог отладочную информацию о входящем сообщении, в том числе точку обмена
+// и ключ маршрутизации, по которым оно было получено.
+func logDelivery(msg amqp091.Delivery) {
+	log := log.Debug().Str("key", msg.RoutingKey)
+	if msg.Exchange != "" {
+		log = log.Str("exchange", msg.Exchange)
+	}
+	if msg.MessageId != "" {
+		log = log.Str("messageId", msg.MessageId)
+	}
+	if msg.Redelivered {
+		log = log.Bool("redelivered", true) // частые повторные доставки говорят о проблемах обработки
+	}
+	log.Msg("delivery")
+}
+
+// nackRequeue определяет, возвращаются ли в очередь сообщения, обработка которых завершилась ошибкой.
+var nackRequeue = false
+
+// SetDefaultNackBehavior задаёт, возвращать ли в очередь сообщения, обработка которых завершилась ошибкой.
+// По умолчанию такие сообщения не возвращаются (и попадают в dead-letter, если он настроен), чтобы избежать
+// бесконечной повторной обработки сообщений, которые не могут быть обработаны.
+//
+// Не является потокобезопасным методом и рекомендуется переопределять перед началом работы с библиотекой.
+func SetDefaultNackBehavior(requeue bool) {
+	nackRequeue = requeue
+}
+
+// consumerTagPrefix используется как префикс имени обработчика, если оно не задано явно.
+var consumerTagPrefix, _ = os.Hostname()
+
+// SetConsumerTagPrefix задаёт префикс имени обработчиков сообщений, для которых имя не задано через WithName.
+// По умолчанию используется имя хоста. Такие обработчики получают имя вида <prefix>-<queue>-<pid>-<n>, что
+// позволяет легко отличать их в интерфейсе управления RabbitMQ. Порядковый номер n делает имя уникальным,
+// даже если на одном канале запущено несколько обработчиков одной очереди.
+//
+// Не является потокобезопасным методом и рекомендуется переопределять перед началом работы с библиотекой.
+func SetConsumerTagPrefix(prefix string) {
+	consumerTagPrefix = prefix
+}
+
+// consumerSeq задаёт порядковый номер имени обработчика внутри процесса.
+var consumerSeq uint64
+
+// consumerTag возвращает новое имя обработчика для указанной очереди с учётом настроек.
+func consumerTag(queue string, options consumeOptions) string {
+	if options.name != "" || options.anonymous {
+		return options.name
+	}
+	seq := atomic.AddUint64(&consumerSeq, 1)
+	return consumerTagPrefix + "-" + queue + "-" + strconv.Itoa(os.Getpid()) + "-" + strconv.FormatUint(seq, 10)
+}
+
+// consume возвращает инициализатор, который декларирует очередь, запускает получение из неё сообщений
+// и передаёт канал с ними в функцию worker, выполняемую в отдельной горутине.
+func consume(queue *Queue, options consumeOptions, worker func(<-chan amqp091.Delivery)) Initializer {
 	log := log.With().Stringer("queue", queue).Logger()
 	log.Debug().Msg("init consumer")
 
-	options := getConsumeOptions(opts) // обобщаем параметры настройки
 	// функция инициализации соединения
 	initializer := func(ch *amqp091.Channel) error {
-		// инициализируем настройки для очереди
-		if err := queue.declare(ch); err != nil {
-			return err
-		}
-
-		// инициализируем получение сообщений
-		consumer, err := ch.Consume(
-			queue.String(),     // queue
-			options.name,       // consumer
-			!options.noAutoAck, // auto-ack
-			options.exclusive,  // exclusive
-			options.noLocal,    // no-local
-			options.noWait,     // no-wait
-			options.args,       // args
-		)
-		log.Debug().Err(err).Msg("init consume worker")
+		consumer, tag, err := startConsume(ch, queue, options)
 		if err != nil {
 			return err
 		}
+		if options.stopSignal != nil {
+			onShutdown(ch, func() { options.stopSignal(ch, tag) })
+		}
+		trackConsumer(ch, tag)
 
-		go func() {
-			// получаем сообщения и вызываем их обработчик
-			for msg := range consumer {
-				handler(msg)
-			}
+		goWorker(ch, func() {
+			worker(consumer)
 			log.Debug().Msg("consumer worker closed")
-		}()
+		})
 
 		return nil
 	}
@@ -52,14 +154,205 @@ func Consume(queue *Queue, handler Handler, opts ...ConsumeOption) Initializer {
 	return initializer
 }
 
+// startConsume декларирует очередь и запускает получение из неё сообщений на указанном канале.
+// Возвращает канал с сообщениями и имя обработчика.
+func startConsume(ch *amqp091.Channel, queue *Queue, options consumeOptions) (<-chan amqp091.Delivery, string, error) {
+	if err := prepareConsume(ch, queue, options); err != nil {
+		return nil, "", err
+	}
+	return consumeQueue(ch, queue, consumerTag(queue.String(), options), options)
+}
+
+// prepareConsume декларирует очередь и выполняет подготовку перед началом получения сообщений.
+func prepareConsume(ch *amqp091.Channel, queue *Queue, options consumeOptions) error {
+	// эксклюзивная очередь и так доступна только текущему соединению
+	if queue.Exclusive && options.exclusive {
+		log.Warn().Stringer("queue", queue).
+			Msg("exclusive consumer on exclusive queue is redundant: did you mean a shared queue?")
+	}
+
+	// ограничение вызывается до начала получения сообщений, поэтому действует уже для первого из них;
+	// некоторые конфигурации требуют задавать его до любых операций с очередями на канале
+	if options.qos > 0 && options.qosBeforeDeclare {
+		if err := setQos(ch, queue, options.qos); err != nil {
+			return err
+		}
+	}
+
+	if err := validateTable(options.args); err != nil {
+		return err
+	}
+
+	// инициализируем настройки для очереди
+	if err := queue.declare(ch); err != nil {
+		return err
+	}
+
+	if options.qos > 0 && !options.qosBeforeDeclare {
+		if err := setQos(ch, queue, options.qos); err != nil {
+			return err
+		}
+	}
+
+	// выполняем подготовку перед началом получения сообщений, если она задана
+	if options.preStart != nil {
+		if err := options.preStart(ch); err != nil {
+			log.Err(err).Stringer("queue", queue).Msg("consumer pre-start")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setQos ограничивает количество неподтверждённых сообщений, передаваемых обработчику канала.
+func setQos(ch *amqp091.Channel, queue *Queue, count int) error {
+	if err := ch.Qos(count, 0, false); err != nil {
+		log.Err(err).Stringer("queue", queue).Msg("consumer qos")
+		return err
+	}
+	return nil
+}
+
+// consumeChannel описывает методы канала, используемые для управления получением сообщений.
+type consumeChannel interface {
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool,
+		args amqp091.Table) (<-chan amqp091.Delivery, error)
+	Cancel(consumer string, noWait bool) error
+	IsClosed() bool
+}
+
+// consumeQueue запускает получение сообщений из уже задекларированной очереди. Если имя обработчика не задано,
+// то оно генерируется, так как необходимо для отмены получения сообщений. Возвращает канал с сообщениями
+// и имя обработчика.
+func consumeQueue(ch consumeChannel, queue *Queue, tag string,
+	options consumeOptions) (<-chan amqp091.Delivery, string, error) {
+	if tag == "" {
+		id, err := newCorrelationID()
+		if err != nil {
+			return nil, "", err
+		}
+		tag = "ctag-" + id
+	}
+
+	consumer, err := ch.Consume(
+		queue.String(),     // queue
+		tag,                // consumer
+		!options.noAutoAck, // auto-ack
+		options.exclusive,  // exclusive
+		options.noLocal,    // no-local
+		options.noWait,     // no-wait
+		options.args,       // args
+	)
+	log.Debug().Err(err).Stringer("queue", queue).Msg("init consume worker")
+	return consumer, tag, err
+}
+
+// ConsumeChan возвращает инициализатор получения сообщений из очереди без их обработчика и функцию,
+// возвращающую текущий канал с входящими сообщениями. Это позволяет самостоятельно управлять получением
+// сообщений, сохраняя автоматическое восстановление соединения.
+//
+// После переподключения функция возвращает уже новый канал, а предыдущий закрывается. До первой
+// инициализации возвращается nil.
+func ConsumeChan(queue *Queue, opts ...ConsumeOption) (Initializer, func() <-chan amqp091.Delivery) {
+	options := getConsumeOptions(opts)
+	var current atomic.Value // текущий канал с сообщениями
+
+	initializer := func(ch *amqp091.Channel) error {
+		consumer, tag, err := startConsume(ch, queue, options)
+		if err != nil {
+			return err
+		}
+		if options.stopSignal != nil {
+			onShutdown(ch, func() { options.stopSignal(ch, tag) })
+		}
+		trackConsumer(ch, tag)
+		current.Store(consumer)
+		return nil
+	}
+
+	deliveries := func() <-chan amqp091.Delivery {
+		consumer, _ := current.Load().(<-chan amqp091.Delivery)
+		return consumer
+	}
+
+	return initializer, deliveries
+}
+
 // consumeOptions описывает поддерживаемые параметры для инициализации обработки сообщений.
 type consumeOptions struct {
 	name      string // название
+	anonymous bool   // не задавать имя по умолчанию
 	noAutoAck bool   // не подтверждать автоматически приём
+	autoAck   bool   // явно задано автоматическое подтверждение
 	exclusive bool   // единоличный доступ
 	noLocal   bool
-	noWait    bool
+	noWait    bool          // не ждать подтверждения от сервера
 	args      amqp091.Table // дополнительные параметры
+
+	contentType   string        // допустимый тип содержимого сообщений
+	preStart      Initializer   // подготовка перед началом получения сообщений
+	maxDeliveries int           // ограничение количества доставок сообщения
+	maxAge        time.Duration // максимальный возраст обрабатываемых сообщений
+
+	qos              int  // ограничение количества неподтверждённых сообщений
+	qosBeforeDeclare bool // задавать ограничение до декларации очереди
+
+	inflightLog time.Duration // интервал записи в лог количества неподтверждённых сообщений
+
+	maxSize    int     // максимальный размер тела сообщения
+	onOversize Handler // обработчик слишком больших сообщений
+
+	stopSignal func(ch *amqp091.Channel, tag string) // вызывается при плановом завершении работы
+
+	heartbeatLog time.Duration // интервал записи в лог о долгой обработке сообщения
+
+	partitions   int                           // количество параллельных обработчиков
+	partitionKey func(amqp091.Delivery) string // ключ для распределения сообщений по обработчикам
+}
+
+// reject проверяет входящее сообщение на соответствие настройкам. Если сообщение не проходит проверку,
+// то оно отклоняется без возврата в очередь (при ручном подтверждении) и не передаётся обработчику.
+// Устаревшие сообщения (смотри WithMaxAge) не отклоняются, а подтверждаются без обработки.
+func (o consumeOptions) reject(msg amqp091.Delivery) bool {
+	if o.maxAge > 0 && !msg.Timestamp.IsZero() && time.Since(msg.Timestamp) > o.maxAge {
+		log.Debug().Str("messageId", msg.MessageId).Time("timestamp", msg.Timestamp).
+			Msg("message is too old: skipped")
+		if o.noAutoAck {
+			if err := msg.Ack(false); err != nil {
+				log.Err(err).Msg("skip message")
+			}
+		}
+		return true
+	}
+
+	oversize := o.maxSize > 0 && len(msg.Body) > o.maxSize
+	if oversize && o.onOversize != nil {
+		log.Warn().Str("messageId", msg.MessageId).Int("size", len(msg.Body)).
+			Msg("message is too large: passed to oversize handler")
+		o.onOversize(msg)
+		return true
+	}
+
+	switch {
+	case oversize:
+		log.Warn().Str("messageId", msg.MessageId).Int("size", len(msg.Body)).
+			Msg("message is too large: message rejected")
+	case o.contentType != "" && msg.ContentType != o.contentType:
+		log.Warn().Str("messageId", msg.MessageId).Str("contentType", msg.ContentType).
+			Msg("unexpected content type: message rejected")
+	case o.maxDeliveries > 0 && deliveredTooMuch(msg, o.maxDeliveries):
+		log.Warn().Str("messageId", msg.MessageId).Msg("delivery limit exceeded: message rejected")
+	default:
+		return false
+	}
+
+	if o.noAutoAck {
+		if err := msg.Nack(false, false); err != nil {
+			log.Err(err).Msg("reject message")
+		}
+	}
+	return true
 }
 
 // getOptions возвращает настройки после применения всех изменений.
@@ -71,12 +364,16 @@ func getConsumeOptions(opts []ConsumeOption) consumeOptions {
 	return options
 }
 
-// ConsumeOption изменяет настройки получения сообщений.
-type ConsumeOption interface{ apply(*consumeOptions) }
+// ConsumeOption изменяет настройки получения сообщений. Может использоваться и как опция WorkWithOptions.
+type ConsumeOption interface {
+	apply(*consumeOptions)
+	WorkOption
+}
 
 type funcConsumeOption struct{ f func(*consumeOptions) }
 
-func (fco *funcConsumeOption) apply(co *consumeOptions) { fco.f(co) }
+func (fco *funcConsumeOption) apply(co *consumeOptions)  { fco.f(co) }
+func (fco *funcConsumeOption) applyWork(wo *workOptions) { wo.consume = append(wo.consume, fco) }
 
 func newFuncConsumeOption(f func(*consumeOptions)) *funcConsumeOption {
 	return &funcConsumeOption{f: f}
@@ -87,20 +384,50 @@ func WithName(v string) ConsumeOption {
 	return newFuncConsumeOption(func(c *consumeOptions) { c.name = v })
 }
 
+// WithAnonymous отключает формирование имени обработчика по умолчанию из префикса и имени очереди: вместо него
+// используется случайное имя вида ctag-<id>, которое генерируется на стороне клиента, так как имя необходимо
+// для отмены получения сообщений.
+func WithAnonymous() ConsumeOption {
+	return newFuncConsumeOption(func(c *consumeOptions) { c.anonymous = true })
+}
+
+// WithAutoAck включает автоматическое подтверждение приёма сообщений сразу при их получении. Для Consume
+// это поведение по умолчанию, а WorkWithOptions по умолчанию подтверждает сообщения только после их
+// обработки: опция возвращает прежнее поведение, при котором сообщение теряется, если обработка не завершилась.
+func WithAutoAck() ConsumeOption {
+	return newFuncConsumeOption(func(c *consumeOptions) {
+		c.autoAck = true
+		c.noAutoAck = false
+	})
+}
+
 // WithNoAutoAck запрещает автоматическое подтверждение приёма сообщений.
 func WithNoAutoAck() ConsumeOption {
 	return newFuncConsumeOption(func(c *consumeOptions) { c.noAutoAck = true })
 }
 
-// WithExclusive взводит флаг эксклюзивного доступа к очереди.
+// WithExclusive взводит флаг эксклюзивного обработчика: он становится единственным получателем сообщений
+// из очереди. Синоним WithExclusiveConsumer.
 func WithExclusive() ConsumeOption {
 	return newFuncConsumeOption(func(c *consumeOptions) { c.exclusive = true })
 }
 
+// WithExclusiveConsumer делает обработчик единственным получателем сообщений из общей очереди: сервер
+// отказывает в подключении другим обработчикам, пока этот активен. Сама очередь при этом остаётся доступна
+// остальным соединениям, например для публикации.
+//
+// Не путайте с эксклюзивной очередью (смотри NewExclusiveQueue), которая принадлежит соединению
+// и удаляется при его закрытии.
+func WithExclusiveConsumer() ConsumeOption {
+	return WithExclusive()
+}
+
 func WithNoLocal() ConsumeOption {
 	return newFuncConsumeOption(func(c *consumeOptions) { c.noLocal = true })
 }
 
+// WithNoWait отключает ожидание подтверждения начала получения сообщений от сервера.
+// В этом случае ошибки не возвращаются при инициализации, а приводят к асинхронному закрытию канала.
 func WithNoWait() ConsumeOption {
 	return newFuncConsumeOption(func(c *consumeOptions) { c.noWait = true })
 }
@@ -109,3 +436,129 @@ func WithNoWait() ConsumeOption {
 func WithArgs(v amqp091.Table) ConsumeOption {
 	return newFuncConsumeOption(func(c *consumeOptions) { c.args = v })
 }
+
+// WithRequireContentType задаёт обязательный тип содержимого входящих сообщений. Сообщения с другим типом
+// не передаются обработчику, а записываются в лог и отклоняются без возврата в очередь.
+func WithRequireContentType(v string) ConsumeOption {
+	return newFuncConsumeOption(func(c *consumeOptions) { c.contentType = v })
+}
+
+// WithPreStart задаёт функцию, которая выполняется при каждом подключении после декларации очереди, но до начала
+// получения сообщений. Например, она может загрузить кеш, необходимый для их обработки. Ошибка функции
+// прерывает инициализацию и приводит к повторному подключению.
+func WithPreStart(v Initializer) ConsumeOption {
+	return newFuncConsumeOption(func(c *consumeOptions) { c.preStart = v })
+}
+
+// WithMaxDeliveries ограничивает количество доставок одного сообщения: сообщения, которые уже были доставлены
+// n или более раз, не передаются обработчику, а отклоняются без возврата в очередь (и попадают в dead-letter,
+// если он настроен). Количество доставок определяется функцией DeliveryCount.
+func WithMaxDeliveries(n int) ConsumeOption {
+	return newFuncConsumeOption(func(c *consumeOptions) { c.maxDeliveries = n })
+}
+
+// WithMaxAge пропускает сообщения, с момента создания которых (по полю Timestamp) прошло больше указанного
+// времени: такие сообщения подтверждаются без передачи обработчику. Это позволяет не обрабатывать устаревшие
+// события, накопившиеся в очереди, например, во время недоступности сервиса.
+// Сообщения без временной метки обрабатываются как обычно.
+func WithMaxAge(v time.Duration) ConsumeOption {
+	return newFuncConsumeOption(func(c *consumeOptions) { c.maxAge = v })
+}
+
+// WithQOS ограничивает количество сообщений, которые сервер передаёт обработчику без подтверждения их приёма
+// (prefetch). Ограничение задаётся для канала до начала получения сообщений, поэтому действует уже для первого
+// из них. Имеет смысл только совместно с WithNoAutoAck.
+func WithQOS(count int) ConsumeOption {
+	return newFuncConsumeOption(func(c *consumeOptions) { c.qos = count })
+}
+
+// WithQOSBeforeDeclare задаёт ограничение WithQOS до декларации очереди, а не после неё. Это требуется
+// для конфигураций, в которых ограничение должно быть задано до любых операций с очередями на канале.
+func WithQOSBeforeDeclare() ConsumeOption {
+	return newFuncConsumeOption(func(c *consumeOptions) { c.qosBeforeDeclare = true })
+}
+
+// WithMaxMessageSize ограничивает размер тела обрабатываемых сообщений, чтобы защитить обработчик от слишком
+// больших сообщений. Такие сообщения не передаются обработчику, а отклоняются без возврата в очередь
+// (и попадают в dead-letter, если он настроен).
+//
+// Если задана функция onOversize, то вместо отклонения сообщение передаётся ей, например, для пересылки
+// в отдельную очередь. При ручном подтверждении она сама отвечает за подтверждение приёма сообщения.
+func WithMaxMessageSize(n int, onOversize Handler) ConsumeOption {
+	return newFuncConsumeOption(func(c *consumeOptions) {
+		c.maxSize = n
+		c.onOversize = onOversize
+	})
+}
+
+// WithStopSignal публикует служебное сообщение при плановом завершении работы Run, например, чтобы сообщить
+// другим сервисам об остановке обработчика. Сообщение создаётся функцией msgFn и публикуется через pub
+// до остановки получения сообщений и закрытия соединения.
+//
+// Публикация ограничена временем StopSignalTimeout, так как контекст Run к этому моменту уже отменён.
+// Для публикации должен использоваться обработчик, запущенный в том же Run. Опция поддерживается всеми
+// обработчиками, запускаемыми через Run, в том числе NewConsumer, ConsumeChan и ConsumeWithDrain; для
+// приостановленного NewConsumer сообщение не публикуется.
+func WithStopSignal(pub Publisher, exchange, key string, msgFn func() amqp091.Publishing) ConsumeOption {
+	return newFuncConsumeOption(func(c *consumeOptions) {
+		c.stopSignal = func(ch *amqp091.Channel, tag string) {
+			ctx, cancel := context.WithTimeout(context.Background(), StopSignalTimeout)
+			defer cancel()
+			if err := pub(ctx, exchange, key, msgFn()); err != nil {
+				log.Err(err).Str("key", key).Msg("publish stop signal")
+			}
+			// прекращаем получение новых сообщений, если известно имя обработчика
+			if tag != "" {
+				if err := ch.Cancel(tag, false); err != nil {
+					log.Err(err).Str("consumer", tag).Msg("cancel consumer")
+				}
+			}
+		}
+	})
+}
+
+// StopSignalTimeout ограничивает время публикации сообщения об остановке обработчика (смотри WithStopSignal).
+var StopSignalTimeout = 5 * time.Second
+
+// watchHandler записывает в лог предупреждение с заданным интервалом, пока не будет вызвана возвращаемая
+// функция остановки. Используется для отслеживания слишком долгой обработки сообщения.
+func watchHandler(msg amqp091.Delivery, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	started := time.Now()
+	done, finished := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				log.Warn().Str("key", msg.RoutingKey).Str("messageId", msg.MessageId).
+					Dur("elapsed", time.Since(started)).Msg("message is still being handled")
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished // после остановки предупреждения больше не записываются
+	}
+}
+
+// WithHeartbeatLog включает запись в лог предупреждения для каждого сообщения, обработка которого длится
+// дольше указанного интервала, и повторяет его с тем же интервалом до завершения обработки. Это позволяет
+// заметить обработчики, которые слишком долго удерживают сообщения.
+//
+// В отличие от SQS, протокол AMQP не позволяет продлить время обработки сообщения: сообщение остаётся
+// неподтверждённым до ответа обработчика или закрытия канала. Сервер может закрыть канал, если сообщение
+// не подтверждено дольше consumer_timeout (по умолчанию 30 минут), и тогда сообщение будет доставлено повторно.
+//
+// Опция действует для обработчиков отдельных сообщений (Consume, ConsumeCtx, WorkWithOptions, NewConsumer и других)
+// и игнорируется ConsumeBatch, который обрабатывает сообщения пакетами.
+func WithHeartbeatLog(interval time.Duration) ConsumeOption {
+	return newFuncConsumeOption(func(c *consumeOptions) { c.heartbeatLog = interval })
+}
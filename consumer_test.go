@@ -0,0 +1,122 @@
+package rabbitmq
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog"
+)
+
+func TestConsumeMaxAge(t *testing.T) {
+	options := getConsumeOptions([]ConsumeOption{WithMaxAge(time.Minute)})
+	tests := []struct {
+		timestamp time.Time
+		skip      bool
+	}{
+		{time.Time{}, false},
+		{time.Now(), false},
+		{time.Now().Add(-time.Hour), true},
+	}
+	for _, test := range tests {
+		if skip := options.reject(amqp091.Delivery{Timestamp: test.timestamp}); skip != test.skip {
+			t.Errorf("timestamp %v: got %v, want %v", test.timestamp, skip, test.skip)
+		}
+	}
+}
+
+func TestConsumeMaxMessageSize(t *testing.T) {
+	ack := new(ackRecorder)
+	small := amqp091.Delivery{Acknowledger: ack, Body: []byte("ok")}
+	large := amqp091.Delivery{Acknowledger: ack, Body: []byte("too large")}
+
+	options := getConsumeOptions([]ConsumeOption{WithNoAutoAck(), WithMaxMessageSize(4, nil)})
+	if options.reject(small) {
+		t.Error("small message rejected")
+	}
+	if !options.reject(large) || ack.nacked != 1 {
+		t.Error("large message not rejected")
+	}
+
+	var oversized int
+	options = getConsumeOptions([]ConsumeOption{
+		WithNoAutoAck(), WithMaxMessageSize(4, func(amqp091.Delivery) { oversized++ }),
+	})
+	if !options.reject(large) || oversized != 1 || ack.nacked != 1 {
+		t.Error("large message not passed to oversize handler")
+	}
+}
+
+// syncBuffer используется для записи лога из нескольких горутин.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) count(s string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Count(b.buf.String(), s)
+}
+
+func TestConsumeHeartbeatLog(t *testing.T) {
+	out := new(syncBuffer)
+	saved := log
+	log = zerolog.New(out)
+	defer func() { log = saved }()
+
+	options := getConsumeOptions([]ConsumeOption{WithHeartbeatLog(5 * time.Millisecond)})
+	worker := handleDeliveries(context.Background(), options, func(_ context.Context, msg amqp091.Delivery) {
+		time.Sleep(30 * time.Millisecond)
+		panic("test")
+	})
+
+	deliveries := make(chan amqp091.Delivery, 1)
+	deliveries <- amqp091.Delivery{MessageId: "1"}
+	close(deliveries)
+	func() {
+		defer func() { _ = recover() }()
+		worker(deliveries)
+	}()
+
+	const warning = "message is still being handled"
+	warnings := out.count(warning)
+	if warnings == 0 {
+		t.Fatal("no warning for long handling")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if n := out.count(warning); n != warnings {
+		t.Errorf("warnings continued after handler panic: %d, then %d", warnings, n)
+	}
+}
+
+func TestConsumerTag(t *testing.T) {
+	var options consumeOptions
+	first, second := consumerTag("tasks", options), consumerTag("tasks", options)
+	if first == second {
+		t.Errorf("consumers of one queue share tag %q", first)
+	}
+	if !strings.HasPrefix(first, consumerTagPrefix+"-tasks-") {
+		t.Errorf("unexpected tag %q", first)
+	}
+	if tag := consumerTag("tasks", getConsumeOptions([]ConsumeOption{WithName("worker")})); tag != "worker" {
+		t.Errorf("explicit name: got %q", tag)
+	}
+
+	// для анонимного обработчика имя генерируется при запуске получения сообщений
+	anonymous := getConsumeOptions([]ConsumeOption{WithAnonymous()})
+	_, tag, err := consumeQueue(new(fakeConsumeChannel), NewQueue("tasks"), consumerTag("tasks", anonymous), anonymous)
+	if err != nil || !strings.HasPrefix(tag, "ctag-") {
+		t.Errorf("anonymous consumer: got %q, %v", tag, err)
+	}
+}
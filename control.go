@@ -0,0 +1,93 @@
+package rabbitmq
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// PublishControl позволяет изменять настройки публикации во время работы, например, при перезагрузке
+// конфигурации, без пересоздания функции публикации и переподключения к серверу.
+// Подключается к функции публикации с помощью опции WithControl. Нулевое значение готово к использованию.
+//
+// Кроме этого, позволяет остановить публикацию и освободить её канал, не затрагивая остальные обработчики
+// соединения (смотри Close).
+type PublishControl struct {
+	mu      sync.Mutex
+	opts    []PublishOption // все применённые опции
+	current atomic.Value    // текущие настройки publishOptions
+	ch      *amqp091.Channel
+	release func() // сбрасывает сохранённую функцию публикации
+	closed  bool
+}
+
+// bind связывает управление с функцией публикации, созданной с указанными опциями.
+func (c *PublishControl) bind(opts []PublishOption, options publishOptions) {
+	c.mu.Lock()
+	c.opts = opts
+	c.current.Store(options)
+	c.mu.Unlock()
+}
+
+// attach сохраняет канал публикации для последующего закрытия и вызывает функцию start, сохраняющую функцию
+// публикации, под той же блокировкой, что и Close. Возвращает false, если публикация уже закрыта.
+func (c *PublishControl) attach(ch *amqp091.Channel, start, release func()) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	c.ch, c.release = ch, release
+	start()
+	return true
+}
+
+// Close останавливает публикацию и закрывает её канал, не затрагивая остальные обработчики соединения.
+// После этого функция публикации возвращает ошибку ErrNoChannel, а при переподключении к серверу
+// публикация не инициализируется. Повторный вызов ничего не делает.
+func (c *PublishControl) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.release != nil {
+		c.release()
+	}
+	if c.ch == nil {
+		return nil
+	}
+	err := c.ch.Close()
+	c.ch = nil
+	log.Debug().Err(err).Msg("publisher closed")
+	return err
+}
+
+// load возвращает текущие настройки публикации.
+func (c *PublishControl) load() publishOptions {
+	return c.current.Load().(publishOptions)
+}
+
+// Update применяет опции к текущим настройкам публикации. Изменения действуют для всех последующих публикаций,
+// а каждая публикация использует согласованный набор настроек, действовавший на момент её начала.
+//
+// Настройки канала (WithInit, WithConfirm, WithConfirmCallback, WithMandatory и WithImmediate) применяются
+// только при следующей инициализации канала, например, после переподключения.
+// До подключения к функции публикации через WithControl изменения игнорируются.
+func (c *PublishControl) Update(opts ...PublishOption) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.opts == nil {
+		return
+	}
+	c.opts = append(c.opts[:len(c.opts):len(c.opts)], opts...)
+	c.current.Store(getPublishOpts(c.opts))
+}
+
+// WithControl подключает к функции публикации управление её настройками во время работы.
+// Одно управление может быть подключено только к одной функции публикации.
+func WithControl(v *PublishControl) PublishOption {
+	return newFuncPublishOption(func(c *publishOptions) { c.control = v })
+}
@@ -0,0 +1,50 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestPublishControl(t *testing.T) {
+	var got amqp091.Publishing
+	var control PublishControl
+	pub, _ := Publish(WithDryRun(), WithAppID("v1"), WithControl(&control),
+		WithPublishInterceptor(func(_ context.Context, _, _ string, msg *amqp091.Publishing) error {
+			got = *msg
+			return nil
+		}))
+
+	if err := pub(context.Background(), "", "test", amqp091.Publishing{}); err != nil {
+		t.Fatal(err)
+	}
+	if got.AppId != "v1" {
+		t.Errorf("appId %q, want v1", got.AppId)
+	}
+
+	control.Update(WithAppID("v2"), WithMessageType("event"))
+	if err := pub(context.Background(), "", "test", amqp091.Publishing{}); err != nil {
+		t.Fatal(err)
+	}
+	if got.AppId != "v2" || got.Type != "event" {
+		t.Errorf("options not updated: appId %q, type %q", got.AppId, got.Type)
+	}
+}
+
+func TestPublishControlClose(t *testing.T) {
+	var control PublishControl
+	pub, _ := Publish(WithControl(&control))
+	if err := control.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := control.Close(); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+	if err := pub(context.Background(), "", "test", amqp091.Publishing{}); err != ErrNoChannel {
+		t.Errorf("got %v, want ErrNoChannel", err)
+	}
+	if control.attach(nil, func() { t.Error("started after close") }, func() {}) {
+		t.Error("attached after close")
+	}
+}
@@ -0,0 +1,79 @@
+package rabbitmq
+
+import (
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Death описывает одну запись заголовка x-death, который сервер добавляет к сообщению
+// при его перемещении в dead-letter точку обмена.
+type Death struct {
+	Count       int64     // сколько раз сообщение попадало в dead-letter по этой причине из этой очереди
+	Reason      string    // причина: rejected, expired, maxlen или delivery_limit
+	Queue       string    // очередь, из которой было удалено сообщение
+	Exchange    string    // точка обмена, в которую сообщение было опубликовано
+	Time        time.Time // время первого попадания в dead-letter
+	RoutingKeys []string  // ключи маршрутизации сообщения
+}
+
+// DeathInfo возвращает разобранное содержимое заголовка x-death входящего сообщения.
+// Если заголовок отсутствует, то возвращается пустой список. Записи с неподдерживаемым форматом пропускаются.
+func DeathInfo(d amqp091.Delivery) []Death {
+	records, _ := d.Headers["x-death"].([]interface{})
+	deaths := make([]Death, 0, len(records))
+	for _, record := range records {
+		table, ok := record.(amqp091.Table)
+		if !ok {
+			continue
+		}
+
+		var death Death
+		death.Count, _ = table["count"].(int64)
+		death.Reason, _ = table["reason"].(string)
+		death.Queue, _ = table["queue"].(string)
+		death.Exchange, _ = table["exchange"].(string)
+		death.Time, _ = table["time"].(time.Time)
+		keys, _ := table["routing-keys"].([]interface{})
+		for _, key := range keys {
+			if key, ok := key.(string); ok {
+				death.RoutingKeys = append(death.RoutingKeys, key)
+			}
+		}
+		deaths = append(deaths, death)
+	}
+
+	return deaths
+}
+
+// DeliveryCount возвращает количество предыдущих доставок сообщения.
+//
+// Для quorum очередей используется заголовок x-delivery-count, который сервер ведёт самостоятельно.
+// Если его нет, то используется сумма счётчиков заголовка x-death. Если нет ни одного из них,
+// то возвращается false.
+func DeliveryCount(d amqp091.Delivery) (int, bool) {
+	switch count := d.Headers["x-delivery-count"].(type) {
+	case int64:
+		return int(count), true
+	case int32:
+		return int(count), true
+	case int:
+		return count, true
+	}
+
+	deaths := DeathInfo(d)
+	if len(deaths) == 0 {
+		return 0, false
+	}
+	var count int
+	for _, death := range deaths {
+		count += int(death.Count)
+	}
+	return count, true
+}
+
+// deliveredTooMuch возвращает true, если сообщение уже было доставлено max или более раз.
+func deliveredTooMuch(d amqp091.Delivery, max int) bool {
+	count, ok := DeliveryCount(d)
+	return ok && count >= max
+}
@@ -0,0 +1,121 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// retryError возвращается инициализатором, когда его необходимо повторить на новом канале
+// без разрыва соединения.
+type retryError struct {
+	err   error
+	delay time.Duration // задержка перед повтором
+}
+
+func (e *retryError) Error() string { return e.err.Error() }
+func (e *retryError) Unwrap() error { return e.err }
+
+// Declare возвращает инициализатор, который только декларирует очередь.
+//
+// Для проверки существования очереди, созданной другим сервисом, используйте флаг Passive у очереди
+// совместно с опцией WithDeclareRetry: тогда при отсутствии очереди декларация будет повторяться на новом канале
+// без переподключения к серверу.
+func Declare(queue *Queue, opts ...DeclareOption) Initializer {
+	log := log.With().Stringer("queue", queue).Logger()
+	options := getDeclareOptions(opts)
+	var attempt int // номер текущей попытки
+	return func(ch *amqp091.Channel) error {
+		err := queue.declare(ch)
+		if err == nil {
+			attempt = 0
+			return nil
+		}
+
+		var amqpErr *amqp091.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp091.NotFound && attempt < options.attempts {
+			attempt++
+			log.Warn().Err(err).Int("attempt", attempt).Msg("queue not found: retry declare")
+			return &retryError{err: err, delay: options.delay}
+		}
+
+		attempt = 0
+		return err
+	}
+}
+
+// declareOptions описывает параметры декларации.
+type declareOptions struct {
+	attempts int           // количество повторов
+	delay    time.Duration // задержка между повторами
+}
+
+// getDeclareOptions возвращает настройки после применения всех изменений.
+func getDeclareOptions(opts []DeclareOption) declareOptions {
+	var options declareOptions
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	return options
+}
+
+// DeclareOption изменяет настройки декларации.
+type DeclareOption interface{ apply(*declareOptions) }
+
+type funcDeclareOption struct{ f func(*declareOptions) }
+
+func (fdo *funcDeclareOption) apply(do *declareOptions) { fdo.f(do) }
+
+func newFuncDeclareOption(f func(*declareOptions)) *funcDeclareOption {
+	return &funcDeclareOption{f: f}
+}
+
+// WithDeclareRetry задаёт количество повторов декларации и задержку между ними, если очередь не найдена.
+// Повтор осуществляется на новом канале без разрыва соединения с сервером.
+func WithDeclareRetry(attempts int, delay time.Duration) DeclareOption {
+	return newFuncDeclareOption(func(c *declareOptions) {
+		c.attempts = attempts
+		c.delay = delay
+	})
+}
+
+// WaitForQueueDepth возвращает инициализатор, который периодически проверяет количество сообщений в очереди
+// и ожидает, пока функция predicate не вернёт true, например, пока очередь не опустеет или не наполнится.
+// Это позволяет согласовать запуск сервисов: инициализаторы, указанные в Run следом, запустятся только после этого.
+// При отмене контекста ожидание прерывается с его ошибкой.
+//
+// Для проверки используется пассивная декларация, поэтому очередь не создаётся. Если очередь ещё не существует,
+// то проверка повторяется с тем же интервалом на новом канале без разрыва соединения.
+func WaitForQueueDepth(ctx context.Context, queue *Queue, predicate func(messages int) bool,
+	poll time.Duration) Initializer {
+	log := log.With().Stringer("queue", queue).Logger()
+	return func(ch *amqp091.Channel) error {
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+		for {
+			state, err := ch.QueueDeclarePassive(
+				queue.String(), queue.Durable, queue.AutoDelete, queue.Exclusive, false, queue.Args)
+			if err != nil {
+				var amqpErr *amqp091.Error
+				if errors.As(err, &amqpErr) && amqpErr.Code == amqp091.NotFound {
+					log.Debug().Msg("queue not found: waiting")
+					return &retryError{err: err, delay: poll} // канал закрыт сервером
+				}
+				return err
+			}
+
+			log.Debug().Int("messages", state.Messages).Msg("queue depth")
+			if predicate(state.Messages) {
+				return nil
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
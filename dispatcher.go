@@ -0,0 +1,88 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Dispatcher распределяет входящие сообщения между обработчиками в зависимости от их типа. По умолчанию
+// тип определяется полем Type сообщения, но вместо него можно использовать заголовок (смотри ByHeader).
+// Нулевое значение готово к использованию. Регистрация обработчиков безопасна во время работы.
+type Dispatcher struct {
+	mu       sync.RWMutex
+	header   string             // заголовок с типом сообщения
+	handlers map[string]Handler // обработчики по типам сообщений
+	fallback Handler            // обработчик сообщений неизвестных типов
+}
+
+// NewDispatcher возвращает новый пустой распределитель сообщений.
+func NewDispatcher() *Dispatcher {
+	return new(Dispatcher)
+}
+
+// ByHeader задаёт заголовок, значение которого используется в качестве типа сообщения вместо поля Type.
+// Возвращает сам распределитель.
+func (d *Dispatcher) ByHeader(key string) *Dispatcher {
+	d.mu.Lock()
+	d.header = key
+	d.mu.Unlock()
+	return d
+}
+
+// Handle регистрирует обработчик для сообщений указанного типа, заменяя ранее зарегистрированный.
+func (d *Dispatcher) Handle(msgType string, handler Handler) {
+	d.mu.Lock()
+	if d.handlers == nil {
+		d.handlers = make(map[string]Handler)
+	}
+	d.handlers[msgType] = handler
+	d.mu.Unlock()
+}
+
+// Default задаёт обработчик для сообщений, тип которых не зарегистрирован.
+func (d *Dispatcher) Default(handler Handler) {
+	d.mu.Lock()
+	d.fallback = handler
+	d.mu.Unlock()
+}
+
+// handler возвращает обработчик для сообщения и его тип.
+func (d *Dispatcher) handler(msg amqp091.Delivery) (Handler, string) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	msgType := msg.Type
+	if d.header != "" {
+		msgType = ""
+		if v, ok := msg.Headers[d.header]; ok {
+			msgType = fmt.Sprint(v)
+		}
+	}
+	if handler, ok := d.handlers[msgType]; ok {
+		return handler, msgType
+	}
+	return d.fallback, msgType
+}
+
+// ConsumeDispatch возвращает инициализированный обработчик входящих сообщений очереди, который передаёт
+// сообщения в обработчики распределителя по их типу. Сообщения неизвестных типов, для которых не задан
+// обработчик по умолчанию, отклоняются без возврата в очередь (при ручном подтверждении).
+func ConsumeDispatch(queue *Queue, d *Dispatcher, opts ...ConsumeOption) Initializer {
+	noAutoAck := getConsumeOptions(opts).noAutoAck
+	return Consume(queue, func(msg amqp091.Delivery) {
+		handler, msgType := d.handler(msg)
+		if handler != nil {
+			handler(msg)
+			return
+		}
+
+		log.Warn().Str("type", msgType).Str("messageId", msg.MessageId).Msg("unknown message type: message rejected")
+		if noAutoAck {
+			if err := msg.Nack(false, false); err != nil {
+				log.Err(err).Msg("reject message")
+			}
+		}
+	}, opts...)
+}
@@ -0,0 +1,38 @@
+package rabbitmq
+
+import (
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestDispatcher(t *testing.T) {
+	var got []string
+	record := func(name string) Handler {
+		return func(amqp091.Delivery) { got = append(got, name) }
+	}
+
+	d := NewDispatcher()
+	d.Handle("OrderCreated", record("created"))
+	d.Handle("OrderPaid", record("paid"))
+
+	for _, msgType := range []string{"OrderPaid", "OrderCreated", "Unknown"} {
+		if handler, _ := d.handler(amqp091.Delivery{Type: msgType}); handler != nil {
+			handler(amqp091.Delivery{})
+		}
+	}
+	if len(got) != 2 || got[0] != "paid" || got[1] != "created" {
+		t.Errorf("unexpected dispatch: %v", got)
+	}
+
+	d.Default(record("default"))
+	d.ByHeader("event")
+	got = nil
+	for _, headers := range []amqp091.Table{{"event": "OrderCreated"}, {"event": "Other"}, nil} {
+		handler, _ := d.handler(amqp091.Delivery{Type: "OrderPaid", Headers: headers})
+		handler(amqp091.Delivery{})
+	}
+	if len(got) != 3 || got[0] != "created" || got[1] != "default" || got[2] != "default" {
+		t.Errorf("unexpected header dispatch: %v", got)
+	}
+}
@@ -0,0 +1,65 @@
+package rabbitmq
+
+import (
+	"errors"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// ErrDLQServerNamed возвращается ConsumeWithDLQ для очереди без имени: имя dead-letter очереди формируется
+// из имени основной, и для очередей с именем от сервера все они использовали бы одну общую очередь ".dlq".
+var ErrDLQServerNamed = errors.New("dead-letter queue requires a named queue")
+
+// ConsumeWithDLQ возвращает инициализированный обработчик входящих сообщений для очереди с настроенной
+// dead-letter очередью, а также описание самой dead-letter очереди.
+//
+// При инициализации декларируется долговременная точка обмена dlx типа direct и связанная с ней
+// долговременная очередь с именем <queue>.dlq, а основной очереди задаются параметры x-dead-letter-exchange
+// и x-dead-letter-routing-key с именем основной очереди. Отклонённые без возврата в очередь и просроченные
+// сообщения основной очереди автоматически попадают в её dead-letter очередь, поэтому одну точку обмена dlx
+// можно использовать для нескольких очередей.
+//
+// Переданное описание очереди не изменяется: параметры задаются для её копии. Основная очередь должна иметь
+// имя, иначе возвращается ошибка ErrDLQServerNamed.
+func ConsumeWithDLQ(queue *Queue, dlx string, handler Handler, opts ...ConsumeOption) (Initializer, *Queue, error) {
+	if queue.Name == "" {
+		return nil, nil, ErrDLQServerNamed
+	}
+	dlq := &Queue{Name: queue.Name + ".dlq", Durable: true}
+
+	// копируем описание и параметры, чтобы не изменять переданную очередь
+	main := *queue
+	main.Args = make(amqp091.Table, len(queue.Args)+2)
+	for k, v := range queue.Args {
+		main.Args[k] = v
+	}
+	main.Args["x-dead-letter-exchange"] = dlx
+	main.Args["x-dead-letter-routing-key"] = queue.Name
+
+	consumer := Consume(&main, handler, opts...)
+	initializer := func(ch *amqp091.Channel) error {
+		err := ch.ExchangeDeclare(
+			dlx,                    // name
+			amqp091.ExchangeDirect, // kind
+			true,                   // durable
+			false,                  // autoDelete
+			false,                  // internal
+			false,                  // noWait
+			nil,                    // args
+		)
+		if err != nil {
+			return err
+		}
+		if err := dlq.declare(ch); err != nil {
+			return err
+		}
+		if err := ch.QueueBind(dlq.String(), queue.Name, dlx, false, nil); err != nil {
+			return err
+		}
+		log.Debug().Stringer("queue", dlq).Str("exchange", dlx).Msg("dead-letter queue declared")
+
+		return consumer(ch)
+	}
+
+	return initializer, dlq, nil
+}
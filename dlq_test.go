@@ -0,0 +1,27 @@
+package rabbitmq
+
+import (
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestConsumeWithDLQ(t *testing.T) {
+	queue := &Queue{Name: "tasks", Args: amqp091.Table{"x-max-length": int32(10)}}
+	init, dlq, err := ConsumeWithDLQ(queue, "dlx", func(amqp091.Delivery) {})
+	if err != nil || init == nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dlq == nil || dlq.Name != "tasks.dlq" {
+		t.Fatalf("unexpected dead-letter queue: %+v", dlq)
+	}
+	if _, ok := queue.Args["x-dead-letter-exchange"]; ok || len(queue.Args) != 1 {
+		t.Errorf("queue args modified: %v", queue.Args)
+	}
+
+	if _, dlq, err := ConsumeWithDLQ(NewPrivateQueue(), "dlx", func(amqp091.Delivery) {}); err != ErrDLQServerNamed {
+		t.Errorf("got %v, want %v", err, ErrDLQServerNamed)
+	} else if dlq != nil {
+		t.Errorf("dead-letter queue for server-named queue: %+v", dlq)
+	}
+}
@@ -0,0 +1,176 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// ConsumeWithDrain возвращает инициализатор обработчика, который получает сообщения одновременно из новой
+// и старой очереди, например, при переходе с классической очереди на кворумную. Сообщения новой очереди
+// обрабатываются в первую очередь.
+//
+// Старая очередь не декларируется, а только проверяется её наличие, поэтому уже удалённая очередь не будет
+// создана заново, а получение из неё просто не запускается. Если из старой очереди в течение idle не поступило
+// ни одного сообщения и она пуста, то получение из неё останавливается, и дальше обрабатывается только новая
+// очередь. Сама старая очередь не удаляется. Проверка старой очереди выполняется на отдельном канале, чтобы
+// её удаление не прерывало получение сообщений из новой; для этого инициализатор должен запускаться через Run,
+// иначе учитывается только отсутствие сообщений в течение idle.
+//
+// Опции применяются к обеим очередям. Если имя обработчика задано через WithName, то для старой очереди к нему
+// добавляется суффикс "-drain".
+func ConsumeWithDrain(oldQueue, newQueue *Queue, handler Handler, idle time.Duration,
+	opts ...ConsumeOption) Initializer {
+	options := getConsumeOptions(opts)
+	oldOptions := options
+	if options.name != "" {
+		oldOptions.name = options.name + "-drain"
+	}
+	old := *oldQueue
+	old.Passive = true // старую очередь не создаём заново
+
+	ctxHandler := func(_ context.Context, msg amqp091.Delivery) { handler(msg) }
+	worker := handleDeliveries(context.Background(), options, ctxHandler)
+	var drained int32 // старая очередь уже обработана
+
+	return func(ch *amqp091.Channel) error {
+		log := log.With().Stringer("queue", newQueue).Str("old", old.Name).Logger()
+		inspect := func() (messages int, found bool, err error) {
+			conn := channelConnection(ch)
+			if conn == nil {
+				return 0, true, nil // проверить очередь на отдельном канале нельзя
+			}
+			inspectCh, err := conn.Channel()
+			if err != nil {
+				return 0, false, err
+			}
+			defer inspectCh.Close()
+
+			state, err := inspectCh.QueueDeclarePassive(old.Name, old.Durable, old.AutoDelete, old.Exclusive,
+				false, old.Args)
+			var amqpErr *amqp091.Error
+			if errors.As(err, &amqpErr) && amqpErr.Code == amqp091.NotFound {
+				return 0, false, nil
+			}
+			return state.Messages, err == nil, err
+		}
+
+		if atomic.LoadInt32(&drained) == 0 {
+			_, found, err := inspect()
+			if err != nil {
+				return err
+			}
+			if !found {
+				atomic.StoreInt32(&drained, 1)
+				log.Info().Msg("old queue not found: drain skipped")
+			}
+		}
+
+		newDeliveries, newTag, err := startConsume(ch, newQueue, options)
+		if err != nil {
+			return err
+		}
+		if options.stopSignal != nil {
+			onShutdown(ch, func() { options.stopSignal(ch, newTag) })
+		}
+		trackConsumer(ch, newTag)
+
+		var oldDeliveries <-chan amqp091.Delivery
+		var oldTag string
+		if atomic.LoadInt32(&drained) == 0 {
+			if oldDeliveries, oldTag, err = startConsume(ch, &old, oldOptions); err != nil {
+				return err
+			}
+			trackConsumer(ch, oldTag)
+		}
+
+		isEmpty := func() bool {
+			messages, found, err := inspect()
+			if err != nil {
+				log.Err(err).Msg("inspect old queue")
+				return false
+			}
+			return !found || messages == 0 // удалённая очередь тоже считается обработанной
+		}
+		cancel := func() error {
+			if err := ch.Cancel(oldTag, false); err != nil {
+				log.Err(err).Msg("cancel old queue consumer")
+				return err
+			}
+			atomic.StoreInt32(&drained, 1)
+			log.Info().Msg("old queue drained: consumer cancelled")
+			return nil
+		}
+
+		deliveries := mergeDrain(newDeliveries, oldDeliveries, idle, isEmpty, cancel)
+		goWorker(ch, func() {
+			worker(deliveries)
+			log.Debug().Msg("consumer worker closed")
+		})
+
+		return nil
+	}
+}
+
+// mergeDrain возвращает канал, в который передаются сообщения из каналов новой и старой очереди с приоритетом
+// новой. Если из старой очереди сообщения не поступали в течение idle и функция isEmpty подтверждает, что она
+// пуста, то вызывается функция cancel, останавливающая получение из неё; если она вернула ошибку, то проверка
+// повторяется через idle. Возвращаемый канал закрывается после закрытия обоих исходных каналов.
+func mergeDrain(newDeliveries, oldDeliveries <-chan amqp091.Delivery, idle time.Duration,
+	isEmpty func() bool, cancel func() error) <-chan amqp091.Delivery {
+	out := make(chan amqp091.Delivery)
+	go func() {
+		defer close(out)
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if oldDeliveries != nil {
+			timer = time.NewTimer(idle)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		last := time.Now() // время получения последнего сообщения из старой очереди
+
+		for newDeliveries != nil || oldDeliveries != nil {
+			// сообщения новой очереди обрабатываются в первую очередь
+			select {
+			case msg, ok := <-newDeliveries:
+				if !ok {
+					newDeliveries = nil
+				} else {
+					out <- msg
+				}
+				continue
+			default:
+			}
+
+			select {
+			case msg, ok := <-newDeliveries:
+				if !ok {
+					newDeliveries = nil
+					continue
+				}
+				out <- msg
+			case msg, ok := <-oldDeliveries:
+				if !ok {
+					oldDeliveries, timeout = nil, nil
+					continue
+				}
+				last = time.Now()
+				out <- msg
+			case <-timeout:
+				if since := time.Since(last); since < idle {
+					timer.Reset(idle - since)
+				} else if isEmpty() && cancel() == nil {
+					timeout = nil // канал старой очереди закроется после подтверждения отмены сервером
+				} else {
+					timer.Reset(idle)
+				}
+			}
+		}
+	}()
+	return out
+}
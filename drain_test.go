@@ -0,0 +1,65 @@
+package rabbitmq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestMergeDrain(t *testing.T) {
+	newDeliveries := make(chan amqp091.Delivery, 10)
+	oldDeliveries := make(chan amqp091.Delivery, 10)
+	for i := uint64(1); i <= 3; i++ {
+		newDeliveries <- amqp091.Delivery{DeliveryTag: i, RoutingKey: "new"}
+		oldDeliveries <- amqp091.Delivery{DeliveryTag: i, RoutingKey: "old"}
+	}
+
+	checks := 0
+	cancelled := make(chan struct{})
+	isEmpty := func() bool {
+		checks++
+		return checks > 1 // при первой проверке очередь ещё не пуста
+	}
+	cancels := 0
+	cancel := func() error {
+		if cancels++; cancels == 1 {
+			return errors.New("cancel failed") // проверка повторяется
+		}
+		close(cancelled)
+		close(oldDeliveries)
+		return nil
+	}
+	out := mergeDrain(newDeliveries, oldDeliveries, 10*time.Millisecond, isEmpty, cancel)
+
+	// сообщения новой очереди, уже доступные для получения, передаются первыми
+	for i := 0; i < 3; i++ {
+		if msg := <-out; msg.RoutingKey != "new" {
+			t.Fatalf("message %d from %s queue, want new", i, msg.RoutingKey)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if msg := <-out; msg.RoutingKey != "old" {
+			t.Fatalf("message %d from %s queue, want old", i, msg.RoutingKey)
+		}
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("old queue consumer not cancelled")
+	}
+	if checks != 3 || cancels != 2 {
+		t.Errorf("old queue checked %d times and cancelled %d times, want 3 and 2", checks, cancels)
+	}
+
+	newDeliveries <- amqp091.Delivery{DeliveryTag: 4, RoutingKey: "new"}
+	close(newDeliveries)
+	if msg := <-out; msg.DeliveryTag != 4 {
+		t.Errorf("got tag %d, want 4", msg.DeliveryTag)
+	}
+	if _, ok := <-out; ok {
+		t.Error("output channel not closed")
+	}
+}
@@ -74,6 +74,23 @@ func ExampleConsume() {
 	}
 }
 
+func ExampleQueue_DeclareIfAbsent() {
+	handler := func(msg amqp091.Delivery) {
+		fmt.Println("->", msg.MessageId)
+	}
+	// очередь может быть уже создана другим сервисом с иными параметрами
+	queue := rabbitmq.NewQueue("test.queue")
+	// обработчик только проверяет наличие очереди, не декларируя её заново
+	consumed := *queue
+	consumed.Passive = true
+	// очередь создаётся только при её отсутствии, после чего запускается обработчик
+	worker := rabbitmq.Chain(queue.DeclareIfAbsent(), consumed.Consume(handler))
+	err := rabbitmq.Init(ctx, addr, worker)
+	if err != nil {
+		panic(err)
+	}
+}
+
 func ExamplePublish() {
 	// инициализируем функцию публикации новых сообщений и соответствующий ей обработчик
 	pubFunc, pubWorker := rabbitmq.Publish(
@@ -126,3 +143,49 @@ func ExampleWork() {
 		panic(err)
 	}
 }
+
+func ExampleDeathInfo() {
+	// входящее сообщение, которое уже дважды попадало в dead-letter
+	msg := amqp091.Delivery{
+		Headers: amqp091.Table{
+			"x-death": []interface{}{
+				amqp091.Table{
+					"count":        int64(2),
+					"reason":       "rejected",
+					"queue":        "test.queue",
+					"exchange":     "",
+					"routing-keys": []interface{}{"test.queue"},
+				},
+			},
+		},
+	}
+
+	for _, death := range rabbitmq.DeathInfo(msg) {
+		fmt.Println(death.Queue, death.Reason, death.Count, death.RoutingKeys)
+	}
+
+	// Output:
+	// test.queue rejected 2 [test.queue]
+}
+
+func ExampleRetryQueue() {
+	// сообщения из очереди повтора через 30 секунд возвращаются в основную очередь
+	queue := rabbitmq.RetryQueue("test.queue.retry", 30*time.Second, "", "test.queue")
+	fmt.Println(queue.Name, queue.Durable)
+	fmt.Println(queue.Args)
+
+	// Output:
+	// test.queue.retry true
+	// map[x-dead-letter-exchange: x-dead-letter-routing-key:test.queue x-message-ttl:30000]
+}
+
+func ExampleDeliveryCount() {
+	// сообщение из quorum очереди, которое уже доставлялось трижды
+	msg := amqp091.Delivery{
+		Headers: amqp091.Table{"x-delivery-count": int64(3)},
+	}
+	fmt.Println(rabbitmq.DeliveryCount(msg))
+
+	// Output:
+	// 3 true
+}
@@ -0,0 +1,77 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// flightCall описывает выполняемый вызов, результат которого ожидают все одновременные вызовы с тем же ключом.
+type flightCall struct {
+	done      chan struct{}
+	err       error
+	cancelled bool // вызов прерван отменой контекста выполнявшего его вызова
+}
+
+// flightGroup объединяет одновременные вызовы с одинаковым ключом в один.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do выполняет функцию fn, если вызов с таким ключом ещё не выполняется, или ожидает завершения уже
+// выполняемого и возвращает его результат. Ожидание прерывается при отмене контекста вызова.
+//
+// Функция fn выполняется с контекстом ctx первого вызова. Если она завершилась из-за отмены этого контекста,
+// то ожидающие вызовы не получают чужую ошибку отмены, а повторяют вызов: один из них выполняет fn заново.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() error) error {
+	for {
+		g.mu.Lock()
+		if call, ok := g.calls[key]; ok {
+			g.mu.Unlock()
+			select {
+			case <-call.done:
+				if call.cancelled {
+					continue // выполнявший вызов отменён: повторяем со своим контекстом
+				}
+				return call.err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if g.calls == nil {
+			g.calls = make(map[string]*flightCall)
+		}
+		call := &flightCall{done: make(chan struct{})}
+		g.calls[key] = call
+		g.mu.Unlock()
+
+		call.err = fn()
+		call.cancelled = call.err != nil && ctx.Err() != nil && errors.Is(call.err, ctx.Err())
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+		return call.err
+	}
+}
+
+// flightKey возвращает ключ объединения публикаций: объединяются только сообщения для одной точки обмена
+// и ключа маршрутизации.
+func flightKey(exchange, key, msgKey string) string {
+	return exchange + "\x00" + key + "\x00" + msgKey
+}
+
+// WithSingleFlight объединяет одновременные публикации сообщений с одинаковым ключом, который возвращает
+// функция keyFn, в одну точку обмена с одним ключом маршрутизации: на сервер отправляется только одно
+// сообщение, а все вызовы получают результат его публикации. Сообщения с пустым ключом публикуются как обычно.
+//
+// Объединяются только публикации, выполняемые одновременно: последовательные публикации с тем же ключом
+// отправляются каждая. Каждый вызов ожидает результат не дольше, чем позволяет его собственный контекст;
+// если публикация прервана отменой контекста выполнявшего её вызова, то ожидающие вызовы повторяют её сами.
+func WithSingleFlight(keyFn func(amqp091.Publishing) string) PublishOption {
+	return newFuncPublishOption(func(c *publishOptions) { c.singleFlight = keyFn })
+}
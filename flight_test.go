@@ -0,0 +1,133 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFlightGroup(t *testing.T) {
+	var (
+		g       flightGroup
+		calls   int32
+		release = make(chan struct{})
+		wg      sync.WaitGroup
+		errTest = errors.New("test")
+		ctx     = context.Background()
+	)
+	fn := func() error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return errTest
+	}
+
+	// первый вызов выполняется, остальные ожидают его результат
+	errs := make([]error, 10)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs[0] = g.do(ctx, "key", fn)
+	}()
+	for atomic.LoadInt32(&calls) == 0 {
+		runtime.Gosched()
+	}
+	for i := 1; i < len(errs); i++ {
+		probe := newProbeContext(ctx)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = g.do(probe, "key", fn)
+		}(i)
+		<-probe.waiting // вызов ожидает результат выполняемого
+	}
+
+	// ожидание прерывается контекстом вызова, не дожидаясь выполняемого
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := g.do(cancelled, "key", fn); err != context.Canceled {
+		t.Errorf("cancelled call: got %v", err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("calls: %d, want 1", n)
+	}
+	for i, err := range errs {
+		if err != errTest {
+			t.Errorf("call %d: got %v", i, err)
+		}
+	}
+	if err := g.do(ctx, "key", func() error { return nil }); err != nil {
+		t.Errorf("sequential call: got %v", err)
+	}
+}
+
+func TestFlightGroupLeaderCancelled(t *testing.T) {
+	var g flightGroup
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	leaderErr := make(chan error, 1)
+	go func() {
+		leaderErr <- g.do(leaderCtx, "key", func() error {
+			close(started)
+			<-leaderCtx.Done()
+			return leaderCtx.Err()
+		})
+	}()
+	<-started
+
+	var calls int32
+	probe := newProbeContext(context.Background())
+	followerErr := make(chan error, 1)
+	go func() {
+		followerErr <- g.do(probe, "key", func() error {
+			atomic.AddInt32(&calls, 1)
+			return nil
+		})
+	}()
+	<-probe.waiting
+	cancel()
+
+	if err := <-leaderErr; err != context.Canceled {
+		t.Errorf("leader: got %v", err)
+	}
+	// ожидающий вызов не получает чужую отмену, а выполняет публикацию сам
+	if err := <-followerErr; err != nil {
+		t.Errorf("follower: got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("follower calls: %d, want 1", n)
+	}
+}
+
+// probeContext сообщает о первом обращении к Done: в flightGroup.do к нему обращаются только вызовы,
+// ожидающие результат уже выполняемого.
+type probeContext struct {
+	context.Context
+	once    sync.Once
+	waiting chan struct{}
+}
+
+func newProbeContext(ctx context.Context) *probeContext {
+	return &probeContext{Context: ctx, waiting: make(chan struct{})}
+}
+
+func (c *probeContext) Done() <-chan struct{} {
+	c.once.Do(func() { close(c.waiting) })
+	return c.Context.Done()
+}
+
+func TestFlightKey(t *testing.T) {
+	if flightKey("ex", "a", "id") == flightKey("ex", "b", "id") ||
+		flightKey("ex1", "a", "id") == flightKey("ex2", "a", "id") {
+		t.Error("publications to different destinations share a key")
+	}
+	if flightKey("ex", "a", "id") != flightKey("ex", "a", "id") {
+		t.Error("same destination and message get different keys")
+	}
+}
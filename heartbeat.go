@@ -0,0 +1,88 @@
+package rabbitmq
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// ErrHeartbeatTimeout передаётся в функцию OnHeartbeat, если сервер не ответил на проверку
+// за отведённый интервал.
+var ErrHeartbeatTimeout = errors.New("heartbeat timeout")
+
+// OnHeartbeat является синонимом функции, получающей результат проверки доступности сервера:
+// время последнего успешного ответа и ошибку текущей проверки.
+type OnHeartbeat = func(last time.Time, err error)
+
+// heartbeatExchange используется для проверки: эта точка обмена всегда существует на сервере.
+const heartbeatExchange = "amq.direct"
+
+// Heartbeat возвращает инициализатор, который с заданным интервалом проверяет доступность сервера и вызывает
+// функцию onHeartbeat с результатом проверки. Это позволяет отслеживать «зависшие» соединения, которые
+// библиотека amqp091-go не обнаруживает между переподключениями.
+//
+// Так как amqp091-go не предоставляет информацию о heartbeat-фреймах, в качестве проверки используется
+// пассивная декларация точки обмена amq.direct, не изменяющая состояние сервера. Если ответ не получен
+// до следующей проверки, то в функцию передаётся ошибка ErrHeartbeatTimeout.
+//
+// При закрытии канала проверка останавливается, а в функцию последний раз передаётся результат незавершённой
+// проверки или ошибка закрытия канала. До переподключения Run проверка не возобновляется: она запускается
+// заново при инициализации нового канала.
+func Heartbeat(interval time.Duration, onHeartbeat OnHeartbeat) Initializer {
+	return func(ch *amqp091.Channel) error {
+		closed := ch.NotifyClose(make(chan *amqp091.Error, 1))
+		check := func() error {
+			return ch.ExchangeDeclarePassive(heartbeatExchange, amqp091.ExchangeDirect,
+				true, false, false, false, nil)
+		}
+		go heartbeat(interval, onHeartbeat, closed, check)
+		return nil
+	}
+}
+
+// heartbeat выполняет проверку check с заданным интервалом до закрытия канала closed и передаёт её результаты
+// в функцию onHeartbeat.
+func heartbeat(interval time.Duration, onHeartbeat OnHeartbeat, closed <-chan *amqp091.Error, check func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := time.Now()     // время последнего ответа сервера
+	var pending chan error // ожидание ответа на текущую проверку
+	for {
+		select {
+		case amqpErr := <-closed:
+			var err error = amqp091.ErrClosed
+			if amqpErr != nil {
+				err = amqpErr
+			}
+			// незавершённая проверка прерывается закрытием канала, поэтому её результат не заставит себя ждать
+			if pending != nil {
+				if checkErr := <-pending; checkErr != nil {
+					err = checkErr
+				} else {
+					last = time.Now()
+				}
+			}
+			onHeartbeat(last, err)
+			log.Debug().Msg("heartbeat stopped")
+			return
+		case err := <-pending:
+			pending = nil
+			if err == nil {
+				last = time.Now()
+			}
+			onHeartbeat(last, err)
+		case <-ticker.C:
+			if pending != nil { // предыдущая проверка ещё не завершилась
+				log.Warn().Time("last", last).Msg("heartbeat timeout")
+				onHeartbeat(last, ErrHeartbeatTimeout)
+				continue
+			}
+			pending = make(chan error, 1)
+			go func(result chan<- error) {
+				result <- check()
+			}(pending)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package rabbitmq
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// heartbeatResults записывает результаты проверок, переданные в OnHeartbeat.
+type heartbeatResults struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (r *heartbeatResults) add(_ time.Time, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, err)
+}
+
+func (r *heartbeatResults) get() []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]error(nil), r.errs...)
+}
+
+func TestHeartbeatClosedPending(t *testing.T) {
+	var (
+		results  heartbeatResults
+		closed   = make(chan *amqp091.Error)
+		started  = make(chan struct{})
+		errCheck = errors.New("channel closed during check")
+		once     sync.Once
+	)
+	// проверка прерывается только закрытием канала
+	check := func() error {
+		once.Do(func() { close(started) })
+		<-closed
+		return errCheck
+	}
+
+	done := make(chan struct{})
+	go func() {
+		heartbeat(time.Millisecond, results.add, closed, check)
+		close(done)
+	}()
+	<-started
+	close(closed)
+	<-done
+
+	// результат незавершённой проверки передаётся до остановки
+	errs := results.get()
+	var reported bool
+	for _, err := range errs {
+		reported = reported || err == errCheck
+	}
+	if !reported || errs[len(errs)-1] == nil {
+		t.Errorf("pending check result not reported: %v", errs)
+	}
+}
+
+func TestHeartbeatClosed(t *testing.T) {
+	var results heartbeatResults
+	closed := make(chan *amqp091.Error)
+	done := make(chan struct{})
+	go func() {
+		heartbeat(time.Hour, results.add, closed, func() error { return nil })
+		close(done)
+	}()
+	close(closed)
+	<-done
+
+	// о закрытии канала без ошибки сообщается ErrClosed
+	if errs := results.get(); len(errs) != 1 || errs[0] != amqp091.ErrClosed {
+		t.Errorf("got %v, want ErrClosed", errs)
+	}
+}
@@ -0,0 +1,102 @@
+package rabbitmq
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// inflightTracker ведёт учёт полученных, но ещё не подтверждённых сообщений обработчика.
+type inflightTracker struct {
+	mu       sync.Mutex
+	tags     map[uint64]struct{} // номера неподтверждённых сообщений
+	prefetch int                 // ограничение количества неподтверждённых сообщений (WithQOS)
+}
+
+func newInflightTracker(prefetch int) *inflightTracker {
+	return &inflightTracker{tags: make(map[uint64]struct{}), prefetch: prefetch}
+}
+
+// track регистрирует полученное сообщение и подменяет его Acknowledger, чтобы отслеживать подтверждение.
+func (t *inflightTracker) track(msg *amqp091.Delivery) {
+	t.mu.Lock()
+	t.tags[msg.DeliveryTag] = struct{}{}
+	t.mu.Unlock()
+	msg.Acknowledger = &inflightAcknowledger{Acknowledger: msg.Acknowledger, tracker: t}
+}
+
+// done удаляет подтверждённые или отклонённые сообщения из учёта.
+func (t *inflightTracker) done(tag uint64, multiple bool) {
+	t.mu.Lock()
+	if multiple {
+		for inflight := range t.tags {
+			if inflight <= tag {
+				delete(t.tags, inflight)
+			}
+		}
+	} else {
+		delete(t.tags, tag)
+	}
+	t.mu.Unlock()
+}
+
+// count возвращает количество неподтверждённых сообщений.
+func (t *inflightTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.tags)
+}
+
+// report записывает в лог количество неподтверждённых сообщений с заданным интервалом.
+// Возвращает функцию для остановки.
+func (t *inflightTracker) report(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				count := t.count()
+				event := log.Debug().Int("inflight", count)
+				if t.prefetch > 0 {
+					event = event.Int("prefetch", t.prefetch).Bool("saturated", count >= t.prefetch)
+				}
+				event.Msg("consumer inflight")
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// inflightAcknowledger передаёт подтверждения сообщений в канал, отмечая их в inflightTracker.
+type inflightAcknowledger struct {
+	amqp091.Acknowledger
+	tracker *inflightTracker
+}
+
+func (a *inflightAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.tracker.done(tag, multiple)
+	return a.Acknowledger.Ack(tag, multiple)
+}
+
+func (a *inflightAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.tracker.done(tag, multiple)
+	return a.Acknowledger.Nack(tag, multiple, requeue)
+}
+
+func (a *inflightAcknowledger) Reject(tag uint64, requeue bool) error {
+	a.tracker.done(tag, false)
+	return a.Acknowledger.Reject(tag, requeue)
+}
+
+// WithInflightLog включает периодическую запись в лог (на уровне debug) количества полученных, но ещё
+// не подтверждённых сообщений, а также признака достижения ограничения WithQOS. Это помогает понять,
+// что ограничивает скорость обработки: медленный обработчик или слишком маленькое ограничение.
+// Имеет смысл только совместно с WithNoAutoAck.
+func WithInflightLog(interval time.Duration) ConsumeOption {
+	return newFuncConsumeOption(func(c *consumeOptions) { c.inflightLog = interval })
+}
@@ -0,0 +1,33 @@
+package rabbitmq
+
+import (
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestInflightTracker(t *testing.T) {
+	tracker := newInflightTracker(10)
+	ack := new(ackRecorder)
+	msgs := make([]amqp091.Delivery, 5)
+	for i := range msgs {
+		msgs[i] = amqp091.Delivery{Acknowledger: ack, DeliveryTag: uint64(i + 1)}
+		tracker.track(&msgs[i])
+	}
+	if count := tracker.count(); count != 5 {
+		t.Fatalf("inflight %d, want 5", count)
+	}
+
+	msgs[0].Reject(false)
+	msgs[2].Ack(true) // подтверждает и второе сообщение
+	if count := tracker.count(); count != 2 {
+		t.Errorf("inflight %d, want 2", count)
+	}
+	msgs[4].Nack(false, true)
+	if count := tracker.count(); count != 1 {
+		t.Errorf("inflight %d, want 1", count)
+	}
+	if ack.acked != 1 || ack.nacked != 2 {
+		t.Errorf("acknowledger calls: %+v", ack)
+	}
+}
@@ -0,0 +1,53 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// ConsumeInto возвращает инициализатор получения сообщений из очереди и канал, в который передаются
+// декодированные из JSON тела сообщений. Это позволяет подключить очередь напрямую к обработке данных
+// через каналы Go.
+//
+// Канал не меняется при переподключении к серверу и никогда не закрывается. Приём сообщения подтверждается
+// после передачи значения в канал, поэтому пока значение не прочитано, новые сообщения не обрабатываются.
+// Если контекст отменён раньше, чем значение прочитано, то сообщение возвращается в очередь. Обычно
+// используется тот же контекст, что передаётся в Run, чтобы плановая остановка не ожидала чтения из канала.
+// Сообщения, которые не удалось декодировать, отклоняются без возврата в очередь, а ошибка передаётся
+// в функцию onError, если она задана. Опция WithNoAutoAck добавляется автоматически.
+//
+// Доставка выполняется как минимум один раз: если соединение с сервером разорвано до подтверждения приёма
+// уже переданного в канал значения, то сервер доставит сообщение повторно, и значение будет получено ещё раз.
+func ConsumeInto[T any](ctx context.Context, queue *Queue, onError func(amqp091.Delivery, error),
+	opts ...ConsumeOption) (Initializer, <-chan T) {
+	out := make(chan T)
+	handler := func(ctx context.Context, msg amqp091.Delivery) {
+		var v T
+		if err := json.Unmarshal(msg.Body, &v); err != nil {
+			log.Warn().Err(err).Str("messageId", msg.MessageId).Msg("decode message: message rejected")
+			if err := msg.Nack(false, false); err != nil {
+				log.Err(err).Msg("reject message")
+			}
+			if onError != nil {
+				onError(msg, err)
+			}
+			return
+		}
+
+		select {
+		case out <- v:
+			if err := msg.Ack(false); err != nil {
+				log.Err(err).Msg("acknowledgement")
+			}
+		case <-ctx.Done(): // значение никто не прочитал: возвращаем сообщение в очередь
+			if err := msg.Nack(false, true); err != nil {
+				log.Err(err).Msg("requeue message")
+			}
+		}
+	}
+
+	opts = append(opts[:len(opts):len(opts)], WithNoAutoAck())
+	return ConsumeCtx(ctx, queue, handler, opts...), out
+}
@@ -0,0 +1,85 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// dispatch передаёт входящие сообщения в функцию обработки до закрытия канала.
+//
+// Если задана параллельная обработка по ключам, то сообщения распределяются между обработчиками по хешу ключа:
+// сообщения с одинаковым ключом всегда обрабатываются одним обработчиком в порядке получения.
+// Возвращает управление после завершения обработки всех сообщений.
+func (o consumeOptions) dispatch(deliveries <-chan amqp091.Delivery, handle func(amqp091.Delivery)) {
+	if o.partitions <= 0 {
+		for msg := range deliveries {
+			handle(msg)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	workers := make([]chan amqp091.Delivery, o.partitions)
+	for i := range workers {
+		workers[i] = make(chan amqp091.Delivery)
+		wg.Add(1)
+		go func(partition <-chan amqp091.Delivery) {
+			defer wg.Done()
+			for msg := range partition {
+				handle(msg)
+			}
+		}(workers[i])
+	}
+
+	for msg := range deliveries {
+		hash := fnv.New32a()
+		hash.Write([]byte(o.partitionKey(msg)))
+		workers[hash.Sum32()%uint32(len(workers))] <- msg
+	}
+
+	for _, partition := range workers {
+		close(partition)
+	}
+	wg.Wait()
+}
+
+// WithPartitionedConcurrency задаёт параллельную обработку сообщений n обработчиками с сохранением порядка
+// внутри ключа, который возвращает функция keyFn (например, идентификатор аккаунта). Сообщения с одинаковым
+// ключом обрабатываются последовательно в порядке получения, а с разными — параллельно.
+//
+// Подтверждения сообщений с одним ключом также выполняются в порядке их получения. Так как медленная обработка
+// одного сообщения задерживает получение следующих, рекомендуется ограничивать их количество через WithQOS.
+//
+// Если функция keyFn не задана, то в качестве ключа используется ключ маршрутизации сообщения.
+func WithPartitionedConcurrency(n int, keyFn func(amqp091.Delivery) string) ConsumeOption {
+	if keyFn == nil {
+		keyFn = func(msg amqp091.Delivery) string { return msg.RoutingKey }
+	}
+	return newFuncConsumeOption(func(c *consumeOptions) {
+		c.partitions = n
+		c.partitionKey = keyFn
+	})
+}
+
+// WithGroupOrdering задаёт обработку сообщений workers обработчиками с сохранением порядка внутри группы,
+// идентификатор которой передаётся в заголовке headerKey. Сообщения одной группы всегда обрабатываются одним
+// обработчиком последовательно в порядке получения, а сообщения разных групп — параллельно. Сообщения без
+// заголовка считаются одной группой.
+//
+// Порядок гарантируется только в пределах одного потребителя: при нескольких потребителях очереди сообщения
+// одной группы могут попасть к разным из них. Подтверждения сообщений разных групп выполняются независимо
+// и не в порядке получения, поэтому обработчик не должен подтверждать сообщения с флагом multiple.
+// Возвращённое в очередь сообщение (nack с requeue) будет доставлено повторно уже после следующих сообщений
+// своей группы, что нарушает их порядок.
+func WithGroupOrdering(headerKey string, workers int) ConsumeOption {
+	return WithPartitionedConcurrency(workers, func(d amqp091.Delivery) string {
+		group, ok := d.Headers[headerKey]
+		if !ok || group == nil {
+			return ""
+		}
+		return fmt.Sprint(group)
+	})
+}
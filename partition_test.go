@@ -0,0 +1,94 @@
+package rabbitmq
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestPartitionedDispatch(t *testing.T) {
+	options := getConsumeOptions([]ConsumeOption{
+		WithPartitionedConcurrency(4, func(d amqp091.Delivery) string { return d.RoutingKey }),
+	})
+
+	deliveries := make(chan amqp091.Delivery)
+	go func() {
+		for i := uint64(1); i <= 100; i++ {
+			key := []string{"a", "b", "c"}[i%3]
+			deliveries <- amqp091.Delivery{RoutingKey: key, DeliveryTag: i}
+		}
+		close(deliveries)
+	}()
+
+	var mu sync.Mutex
+	last := make(map[string]uint64)
+	count := 0
+	options.dispatch(deliveries, func(d amqp091.Delivery) {
+		mu.Lock()
+		defer mu.Unlock()
+		if d.DeliveryTag <= last[d.RoutingKey] {
+			t.Errorf("key %s: tag %d after %d", d.RoutingKey, d.DeliveryTag, last[d.RoutingKey])
+		}
+		last[d.RoutingKey] = d.DeliveryTag
+		count++
+	})
+
+	if count != 100 {
+		t.Errorf("handled %d messages, want 100", count)
+	}
+}
+
+func TestGroupOrdering(t *testing.T) {
+	options := getConsumeOptions([]ConsumeOption{WithGroupOrdering("x-group", 3)})
+
+	deliveries := make(chan amqp091.Delivery)
+	go func() {
+		for i := uint64(1); i <= 100; i++ {
+			headers := amqp091.Table{"x-group": int32(i % 5)}
+			if i%7 == 0 {
+				headers = nil // без группы
+			}
+			deliveries <- amqp091.Delivery{Headers: headers, DeliveryTag: i}
+		}
+		close(deliveries)
+	}()
+
+	var mu sync.Mutex
+	last := make(map[interface{}]uint64)
+	count := 0
+	options.dispatch(deliveries, func(d amqp091.Delivery) {
+		mu.Lock()
+		defer mu.Unlock()
+		group := d.Headers["x-group"]
+		if d.DeliveryTag <= last[group] {
+			t.Errorf("group %v: tag %d after %d", group, d.DeliveryTag, last[group])
+		}
+		last[group] = d.DeliveryTag
+		count++
+	})
+
+	if count != 100 {
+		t.Errorf("handled %d messages, want 100", count)
+	}
+}
+
+func TestPartitionedDispatchDefaultKey(t *testing.T) {
+	options := getConsumeOptions([]ConsumeOption{WithPartitionedConcurrency(2, nil)})
+
+	deliveries := make(chan amqp091.Delivery, 2)
+	deliveries <- amqp091.Delivery{RoutingKey: "a"}
+	deliveries <- amqp091.Delivery{RoutingKey: "b"}
+	close(deliveries)
+
+	var mu sync.Mutex
+	count := 0
+	options.dispatch(deliveries, func(amqp091.Delivery) {
+		mu.Lock()
+		count++
+		mu.Unlock()
+	})
+	if count != 2 {
+		t.Errorf("handled %d messages, want 2", count)
+	}
+}
@@ -0,0 +1,127 @@
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Consumer описывает обработчик входящих сообщений очереди, получение которых можно приостановить
+// и возобновить без остановки Run и без влияния на другие обработчики соединения.
+// Состояние приостановки сохраняется и после переподключения к серверу.
+type Consumer struct {
+	queue   *Queue
+	options consumeOptions
+	worker  func(<-chan amqp091.Delivery)
+	tag     string // имя обработчика, необходимое для отмены получения
+
+	mu       sync.Mutex
+	ch       *amqp091.Channel // текущий канал соединения
+	consumer consumeChannel   // методы текущего канала для управления получением сообщений
+	paused   bool
+}
+
+// NewConsumer возвращает управляемый обработчик входящих сообщений для указанной очереди и его инициализатор.
+// Поддерживает те же опции, что и Consume.
+func NewConsumer(queue *Queue, handler Handler, opts ...ConsumeOption) (*Consumer, Initializer) {
+	options := getConsumeOptions(opts)
+	ctxHandler := func(_ context.Context, msg amqp091.Delivery) { handler(msg) }
+	c := &Consumer{
+		queue:   queue,
+		options: options,
+		worker:  handleDeliveries(context.Background(), options, ctxHandler),
+	}
+
+	initializer := func(ch *amqp091.Channel) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if err := prepareConsume(ch, queue, options); err != nil {
+			return err
+		}
+		return c.attach(ch, ch)
+	}
+
+	return c, initializer
+}
+
+// attach сохраняет новый канал соединения и запускает на нём получение сообщений, если оно не приостановлено.
+// Вызывается под блокировкой.
+func (c *Consumer) attach(ch *amqp091.Channel, consumer consumeChannel) error {
+	c.ch, c.consumer = ch, consumer
+	if c.options.stopSignal != nil {
+		onShutdown(ch, func() {
+			c.mu.Lock()
+			tag, paused := c.tag, c.paused
+			c.mu.Unlock()
+			if !paused {
+				c.options.stopSignal(ch, tag)
+			}
+		})
+	}
+	if c.paused {
+		log.Debug().Stringer("queue", c.queue).Msg("consumer paused")
+		return nil
+	}
+	return c.start()
+}
+
+// start запускает получение сообщений на текущем канале.
+func (c *Consumer) start() error {
+	if c.tag == "" {
+		c.tag = consumerTag(c.queue.String(), c.options)
+	}
+
+	deliveries, tag, err := consumeQueue(c.consumer, c.queue, c.tag, c.options)
+	if err != nil {
+		return err
+	}
+	c.tag = tag // для отмены получения имя обработчика должно быть известно
+	trackConsumer(c.ch, tag)
+
+	goWorker(c.ch, func() {
+		c.worker(deliveries)
+		log.Debug().Stringer("queue", c.queue).Msg("consumer worker closed")
+	})
+	return nil
+}
+
+// Pause приостанавливает получение сообщений. Уже полученные сообщения обрабатываются до конца.
+func (c *Consumer) Pause() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused {
+		return nil
+	}
+	c.paused = true
+	log.Debug().Stringer("queue", c.queue).Msg("pause consumer")
+	if c.consumer == nil || c.consumer.IsClosed() {
+		return nil // получение сообщений не будет запущено при подключении
+	}
+	return c.consumer.Cancel(c.tag, false)
+}
+
+// Resume возобновляет получение сообщений после приостановки.
+func (c *Consumer) Resume() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.paused {
+		return nil
+	}
+	c.paused = false
+	log.Debug().Stringer("queue", c.queue).Msg("resume consumer")
+	if c.consumer == nil || c.consumer.IsClosed() {
+		return nil // получение сообщений будет запущено при подключении
+	}
+	return c.start()
+}
+
+// Paused возвращает true, если получение сообщений приостановлено.
+func (c *Consumer) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
@@ -0,0 +1,147 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// fakeConsumeChannel имитирует канал получения сообщений и записывает запуски и отмены обработчиков.
+type fakeConsumeChannel struct {
+	mu        sync.Mutex
+	closed    bool
+	calls     []string
+	consumers map[string]chan amqp091.Delivery
+}
+
+func (c *fakeConsumeChannel) Consume(_, tag string, _, _, _, _ bool,
+	_ amqp091.Table) (<-chan amqp091.Delivery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.consumers == nil {
+		c.consumers = make(map[string]chan amqp091.Delivery)
+	}
+	deliveries := make(chan amqp091.Delivery)
+	c.consumers[tag] = deliveries
+	c.calls = append(c.calls, "consume "+tag)
+	return deliveries, nil
+}
+
+func (c *fakeConsumeChannel) Cancel(tag string, _ bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if deliveries, ok := c.consumers[tag]; ok {
+		close(deliveries)
+		delete(c.consumers, tag)
+	}
+	c.calls = append(c.calls, "cancel "+tag)
+	return nil
+}
+
+func (c *fakeConsumeChannel) IsClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func (c *fakeConsumeChannel) deliver(tag string, msg amqp091.Delivery) {
+	c.mu.Lock()
+	deliveries := c.consumers[tag]
+	c.mu.Unlock()
+	deliveries <- msg
+}
+
+func (c *fakeConsumeChannel) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for tag, deliveries := range c.consumers {
+		close(deliveries)
+		delete(c.consumers, tag)
+	}
+}
+
+func (c *fakeConsumeChannel) get() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.calls...)
+}
+
+// attachConsumer подключает обработчик к новому каналу, как это делает его инициализатор при подключении.
+func attachConsumer(t *testing.T, c *Consumer) *fakeConsumeChannel {
+	t.Helper()
+	ch, fake := new(amqp091.Channel), new(fakeConsumeChannel)
+	bindConnection(ch, nil)
+	t.Cleanup(func() { releaseChannel(ch)() })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.attach(ch, fake); err != nil {
+		t.Fatal(err)
+	}
+	return fake
+}
+
+func TestConsumerPauseResume(t *testing.T) {
+	received := make(chan string, 1)
+	c, _ := NewConsumer(NewQueue("tasks"), func(msg amqp091.Delivery) { received <- msg.MessageId },
+		WithName("worker"))
+
+	ch1 := attachConsumer(t, c)
+	if got := fmt.Sprint(ch1.get()); got != "[consume worker]" {
+		t.Fatalf("first connection: %s", got)
+	}
+	ch1.deliver("worker", amqp091.Delivery{MessageId: "1"})
+	if id := <-received; id != "1" {
+		t.Errorf("received %q", id)
+	}
+
+	// приостановка отменяет получение сообщений, повторный вызов ничего не делает
+	if err := c.Pause(); err != nil || !c.Paused() {
+		t.Fatalf("pause: %v, paused %v", err, c.Paused())
+	}
+	if err := c.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(ch1.get()); got != "[consume worker cancel worker]" {
+		t.Errorf("after pause: %s", got)
+	}
+
+	// после переподключения приостановленный обработчик не запускается
+	ch1.close()
+	ch2 := attachConsumer(t, c)
+	if calls := ch2.get(); len(calls) != 0 {
+		t.Errorf("paused consumer started on reconnect: %v", calls)
+	}
+
+	// возобновление запускает получение на текущем канале с тем же именем обработчика
+	if err := c.Resume(); err != nil || c.Paused() {
+		t.Fatalf("resume: %v, paused %v", err, c.Paused())
+	}
+	if got := fmt.Sprint(ch2.get()); got != "[consume worker]" {
+		t.Errorf("after resume: %s", got)
+	}
+	ch2.deliver("worker", amqp091.Delivery{MessageId: "2"})
+	if id := <-received; id != "2" {
+		t.Errorf("received %q", id)
+	}
+
+	// приостановка при закрытом канале сохраняется до следующего подключения
+	ch2.close()
+	if err := c.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(ch2.get()); got != "[consume worker]" {
+		t.Errorf("cancel on closed channel: %s", got)
+	}
+	if err := c.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	ch3 := attachConsumer(t, c)
+	if got := fmt.Sprint(ch3.get()); got != "[consume worker]" {
+		t.Errorf("resumed consumer on reconnect: %s", got)
+	}
+	ch3.close()
+}
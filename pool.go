@@ -0,0 +1,132 @@
+package rabbitmq
+
+import (
+	"sync"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Pool описывает пул соединений с серверами RabbitMQ, которые совместно используются с подсчётом ссылок.
+// Соединения хранятся по адресу сервера: пока соединение живо, оно возвращается повторно, а закрытое
+// соединение заменяется новым. Пустое значение готово к использованию.
+type Pool struct {
+	mu    sync.Mutex
+	conns map[string]*pooledConn
+
+	dial      func(addr string) (*amqp091.Connection, error) // подключение к серверу; по умолчанию Connect
+	isClosed  func(*amqp091.Connection) bool                 // проверка закрытия; по умолчанию IsClosed
+	closeConn func(*amqp091.Connection)                      // закрытие соединения; по умолчанию Close
+}
+
+// pooledConn описывает соединение пула и количество его пользователей.
+type pooledConn struct {
+	conn *amqp091.Connection
+	refs int
+
+	ready chan struct{} // закрывается после завершения подключения
+	err   error         // ошибка подключения
+}
+
+// connected возвращает true, если подключение уже завершено. Вызывается под блокировкой.
+func (pc *pooledConn) connected() bool {
+	select {
+	case <-pc.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// Get возвращает соединение с сервером по указанному адресу и функцию для его освобождения.
+// Если живого соединения в пуле нет, то оно устанавливается через Connect. Одновременные вызовы с тем же
+// адресом ожидают одного подключения, а подключение к одному серверу не блокирует работу с другими.
+//
+// Функцию release необходимо вызвать после окончания работы с соединением: соединение закрывается,
+// когда его освободят все пользователи. Повторные вызовы release игнорируются.
+func (p *Pool) Get(addr string) (conn *amqp091.Connection, release func(), err error) {
+	p.mu.Lock()
+	pc := p.conns[addr]
+	dial := pc == nil || pc.connected() && p.closed(pc.conn)
+	if dial {
+		if p.conns == nil {
+			p.conns = make(map[string]*pooledConn)
+		}
+		pc = &pooledConn{ready: make(chan struct{})}
+		p.conns[addr] = pc
+	}
+	pc.refs++
+	p.mu.Unlock()
+
+	if dial { // подключаемся без блокировки пула
+		connect := p.dial
+		if connect == nil {
+			connect = func(addr string) (*amqp091.Connection, error) { return Connect(addr) }
+		}
+		conn, err := connect(addr)
+
+		p.mu.Lock()
+		pc.conn, pc.err = conn, err
+		if err != nil && p.conns[addr] == pc {
+			delete(p.conns, addr)
+		}
+		p.mu.Unlock()
+		close(pc.ready)
+		if err == nil {
+			log.Debug().Msg("pool connection opened")
+		}
+	}
+
+	<-pc.ready
+	if pc.err != nil {
+		return nil, nil, pc.err
+	}
+
+	var once sync.Once
+	release = func() { once.Do(func() { p.release(addr, pc) }) }
+	return pc.conn, release, nil
+}
+
+// release уменьшает количество пользователей соединения и закрывает его, если оно больше не используется.
+func (p *Pool) release(addr string, pc *pooledConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pc.refs--; pc.refs > 0 {
+		return
+	}
+	if p.conns[addr] == pc {
+		delete(p.conns, addr)
+	}
+	p.close(pc.conn)
+	log.Debug().Msg("pool connection closed")
+}
+
+// Close закрывает все соединения пула независимо от количества их пользователей.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for addr, pc := range p.conns {
+		if pc.connected() && pc.conn != nil {
+			p.close(pc.conn)
+		}
+		delete(p.conns, addr)
+	}
+}
+
+// closed возвращает true, если соединение закрыто.
+func (p *Pool) closed(conn *amqp091.Connection) bool {
+	if p.isClosed != nil {
+		return p.isClosed(conn)
+	}
+	return conn.IsClosed()
+}
+
+// close закрывает соединение.
+func (p *Pool) close(conn *amqp091.Connection) {
+	if p.closeConn != nil {
+		p.closeConn(conn)
+		return
+	}
+	conn.Close()
+}
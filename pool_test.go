@@ -0,0 +1,194 @@
+package rabbitmq
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// fakeConns подменяет подключение к серверу в пуле и учитывает подключения и закрытия соединений.
+type fakeConns struct {
+	mu     sync.Mutex
+	dials  map[string]int
+	closed map[*amqp091.Connection]bool
+}
+
+func newFakePool() (*Pool, *fakeConns) {
+	fc := &fakeConns{dials: make(map[string]int), closed: make(map[*amqp091.Connection]bool)}
+	pool := &Pool{
+		dial: func(addr string) (*amqp091.Connection, error) {
+			fc.mu.Lock()
+			defer fc.mu.Unlock()
+			fc.dials[addr]++
+			return new(amqp091.Connection), nil
+		},
+		isClosed: func(conn *amqp091.Connection) bool {
+			fc.mu.Lock()
+			defer fc.mu.Unlock()
+			return fc.closed[conn]
+		},
+		closeConn: func(conn *amqp091.Connection) {
+			fc.mu.Lock()
+			defer fc.mu.Unlock()
+			fc.closed[conn] = true
+		},
+	}
+	return pool, fc
+}
+
+func (fc *fakeConns) isClosed(conn *amqp091.Connection) bool {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.closed[conn]
+}
+
+func (fc *fakeConns) dialCount(addr string) int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.dials[addr]
+}
+
+func TestPoolDialOutsideLock(t *testing.T) {
+	pool, fc := newFakePool()
+	dial := pool.dial
+	dialing := make(chan struct{})
+	block := make(chan struct{})
+	pool.dial = func(addr string) (*amqp091.Connection, error) {
+		if addr != "slow" {
+			return dial(addr)
+		}
+		fc.mu.Lock()
+		fc.dials[addr]++
+		fc.mu.Unlock()
+		close(dialing)
+		<-block
+		return nil, errors.New("unreachable")
+	}
+
+	errs := make(chan error, 2)
+	get := func() {
+		_, _, err := pool.Get("slow")
+		errs <- err
+	}
+	go get()
+	<-dialing
+	// второй вызов ожидает уже начатое подключение, а не подключается сам
+	go get()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		pool.mu.Lock()
+		refs := pool.conns["slow"].refs
+		pool.mu.Unlock()
+		if refs == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("second Get does not wait for the pending dial")
+		}
+	}
+
+	// подключение к недоступному серверу не блокирует другие адреса
+	got := make(chan struct{})
+	go func() {
+		if _, _, err := pool.Get("fast"); err != nil {
+			t.Error(err)
+		}
+		close(got)
+	}()
+	select {
+	case <-got:
+	case <-time.After(time.Second):
+		t.Fatal("Get blocked by another address")
+	}
+
+	close(block)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err == nil {
+			t.Error("expected connection error")
+		}
+	}
+	if n := fc.dialCount("slow"); n != 1 {
+		t.Errorf("slow dials: %d, want 1", n)
+	}
+	if n := fc.dialCount("fast"); n != 1 {
+		t.Errorf("fast dials: %d, want 1", n)
+	}
+}
+
+func TestPoolRefCount(t *testing.T) {
+	pool, fc := newFakePool()
+	conn1, release1, err := pool.Get("addr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn2, release2, err := pool.Get("addr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// живое соединение используется повторно
+	if conn1 != conn2 || fc.dialCount("addr") != 1 {
+		t.Fatalf("live connection not reused: %d dials", fc.dialCount("addr"))
+	}
+
+	// соединение закрывается только после освобождения всеми пользователями
+	release1()
+	release1() // повторный вызов игнорируется
+	if fc.isClosed(conn1) {
+		t.Fatal("connection closed while still in use")
+	}
+	release2()
+	if !fc.isClosed(conn1) {
+		t.Fatal("connection not closed after last release")
+	}
+
+	conn3, release3, err := pool.Get("addr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release3()
+	if conn3 == conn1 || fc.dialCount("addr") != 2 {
+		t.Error("released connection reused")
+	}
+}
+
+func TestPoolRedial(t *testing.T) {
+	pool, fc := newFakePool()
+	conn1, release1, err := pool.Get("addr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.closeConn(conn1) // соединение разорвано сервером
+
+	// закрытое соединение заменяется новым
+	conn2, release2, err := pool.Get("addr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn2 == conn1 || fc.dialCount("addr") != 2 {
+		t.Fatalf("closed connection not replaced: %d dials", fc.dialCount("addr"))
+	}
+
+	// освобождение старого соединения не затрагивает новое
+	release1()
+	conn3, release3, err := pool.Get("addr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn3 != conn2 || fc.isClosed(conn2) {
+		t.Error("new connection affected by release of the closed one")
+	}
+	release2()
+	release3()
+
+	// Close закрывает соединения независимо от количества пользователей
+	conn4, _, err := pool.Get("addr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Close()
+	if !fc.isClosed(conn4) {
+		t.Error("connection not closed by Close")
+	}
+}
@@ -3,7 +3,10 @@ package rabbitmq
 import (
 	"context"
 	"errors"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -11,24 +14,211 @@ import (
 )
 
 // Publisher описывает функцию для публикации сообщений на сервер RabbitMQ.
-type Publisher = func(ctx context.Context, exchange, key string, msg amqp091.Publishing) error
+//
+// Функции публикации, возвращаемые Publish и Work, безопасны для одновременного использования из разных
+// горутин: публикация в общий канал соединения выполняется последовательно.
+type Publisher func(ctx context.Context, exchange, key string, msg amqp091.Publishing) error
+
+// PublishToDefault публикует сообщение напрямую в очередь с указанным именем.
+//
+// Для этого используется точка обмена по умолчанию (с пустым именем), которая связана со всеми очередями
+// и в качестве ключа маршрутизации использует название очереди. Вызов эквивалентен p(ctx, "", queue, msg).
+func (p Publisher) PublishToDefault(ctx context.Context, queue string, msg amqp091.Publishing) error {
+	return p(ctx, "", queue, msg)
+}
+
+// PublishHeaders публикует сообщение в точку обмена типа headers. Для таких точек обмена маршрутизация
+// определяется только заголовками сообщения, поэтому заданные заголовки добавляются к заголовкам сообщения
+// (с заменой совпадающих), а ключ маршрутизации не используется.
+func (p Publisher) PublishHeaders(ctx context.Context, exchange string, headers amqp091.Table,
+	msg amqp091.Publishing) error {
+	merged := make(amqp091.Table, len(msg.Headers)+len(headers))
+	for k, v := range msg.Headers {
+		merged[k] = v
+	}
+	for k, v := range headers {
+		merged[k] = v
+	}
+	msg.Headers = merged
+	return p(ctx, exchange, "", msg)
+}
+
+// TombstoneHeader задаёт заголовок, которым отмечаются сообщения-надгробия (смотри PublishTombstone).
+const TombstoneHeader = "x-tombstone"
+
+// PublishTombstone публикует сообщение-надгробие: сообщение с пустым телом и заголовком TombstoneHeader,
+// означающее удаление данных с указанным ключом. Для хранения последнего состояния по ключам (например,
+// в stream очередях) получатели должны удалять ранее сохранённые по этому ключу данные. Проверить сообщение
+// можно с помощью IsTombstone.
+//
+// Сам RabbitMQ не выполняет уплотнение stream очередей по ключам: обработка надгробий полностью
+// остаётся на стороне получателей.
+func (p Publisher) PublishTombstone(ctx context.Context, exchange, key string) error {
+	return p(ctx, exchange, key, amqp091.Publishing{
+		Headers: amqp091.Table{TombstoneHeader: true},
+	})
+}
+
+// IsTombstone возвращает true, если полученное сообщение является надгробием (смотри PublishTombstone).
+func IsTombstone(d amqp091.Delivery) bool {
+	tombstone, _ := d.Headers[TombstoneHeader].(bool)
+	return tombstone && len(d.Body) == 0
+}
+
+// PublishMany публикует список сообщений в одну точку обмена с одним ключом маршрутизации, применяя к каждому
+// все настройки публикации. Ошибка публикации одного сообщения не прерывает отправку остальных.
+// Если какие-то сообщения не были опубликованы, то возвращается ошибка PublishErrors.
+//
+// При публикации с подтверждением (WithConfirm) сначала отправляются все сообщения, а затем ожидаются
+// подтверждения сервера для них, а не для каждого сообщения по очереди.
+func (p Publisher) PublishMany(ctx context.Context, exchange, key string, msgs []amqp091.Publishing) error {
+	type pendingConfirm struct {
+		index  int
+		result <-chan error
+	}
+	var (
+		mu       sync.Mutex
+		confirms []pendingConfirm
+		index    int // индекс публикуемого сообщения
+		errs     PublishErrors
+	)
+	pubCtx := withConfirmSink(ctx, func(result <-chan error) {
+		mu.Lock()
+		confirms = append(confirms, pendingConfirm{index: index, result: result})
+		mu.Unlock()
+	})
+	for i, msg := range msgs {
+		mu.Lock()
+		index = i
+		mu.Unlock()
+		if err := p(pubCtx, exchange, key, msg); err != nil {
+			errs = append(errs, &PublishError{Index: i, Err: err})
+		}
+	}
+
+	// подтверждения приходят по порядку, поэтому фактически ожидается подтверждение последнего сообщения
+	mu.Lock()
+	pending := confirms
+	mu.Unlock()
+	for _, c := range pending {
+		var err error
+		select {
+		case err = <-c.result:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err != nil {
+			errs = append(errs, &PublishError{Index: c.index, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Index < errs[j].Index })
+		return errs
+	}
+	return nil
+}
+
+// PublishError описывает ошибку публикации одного сообщения из списка.
+type PublishError struct {
+	Index int   // индекс сообщения в списке
+	Err   error // ошибка публикации
+}
+
+func (e *PublishError) Error() string {
+	return "message " + strconv.Itoa(e.Index) + ": " + e.Err.Error()
+}
+func (e *PublishError) Unwrap() error { return e.Err }
+
+// PublishErrors описывает ошибки публикации сообщений при вызове PublishMany.
+type PublishErrors []*PublishError
+
+func (e PublishErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap возвращает ошибки публикации отдельных сообщений.
+func (e PublishErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Is и As позволяют проверять ошибки отдельных сообщений через errors.Is и errors.As и в версиях Go,
+// которые не поддерживают Unwrap со списком ошибок.
+func (e PublishErrors) Is(target error) bool { return errorsIs(e.Unwrap(), target) }
+func (e PublishErrors) As(target any) bool   { return errorsAs(e.Unwrap(), target) }
+
+// errorsIs возвращает true, если хотя бы одна из ошибок соответствует target.
+func errorsIs(errs []error, target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorsAs находит первую из ошибок, соответствующую target, и присваивает её target.
+func errorsAs(errs []error, target any) bool {
+	for _, err := range errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
 
 // ErrNoChannel описывает ошибку не инициализированного канала.
 var ErrNoChannel = errors.New("channel is not initialized")
 
+// ErrChannelClosed возвращается, если канал публикации был закрыт во время отправки сообщения, например,
+// при разрыве соединения. Обычно это временная ошибка: после переподключения публикацию можно повторить.
+var ErrChannelClosed = errors.New("publishing channel is closed")
+
+// publishError заменяет ошибку закрытого канала amqp091 на ErrChannelClosed.
+func publishError(err error) error {
+	if errors.Is(err, amqp091.ErrClosed) {
+		return ErrChannelClosed
+	}
+	return err
+}
+
 // Publish возвращает функцию и обработчик для публикации сообщений.
 //
 // Если перед публикацией необходимо произвести некоторые настройки канала, то можно задать свою функцию инициализации
 // с помощью опции WithInit(ChannelHandler).
+//
+// Публикация может заблокироваться, если сервер приостановил приём сообщений, поэтому рекомендуется передавать
+// контекст с ограничением времени или задать его по умолчанию опцией WithPublishTimeout.
 func Publish(opts ...PublishOption) (Publisher, Initializer) {
 	log.Debug().Msg("init publisher")
 
 	options := getPublishOpts(opts)       // суммарные опции для публикации
 	var storedPublishingFunc atomic.Value // для ссылки на функцию публикации
+	var flights flightGroup               // одновременные публикации с одинаковым ключом
+	loadOptions := func() publishOptions { return options }
+	if control := options.control; control != nil {
+		control.bind(opts, options)
+		loadOptions = control.load // настройки могут изменяться во время работы
+	}
+	if options.dryRun {
+		log.Warn().Msg("publisher in dry-run mode: messages will not be sent")
+	}
+	if options.immediate {
+		log.Warn().Msg("immediate flag is not supported by RabbitMQ 3.0+ and will close the publishing channel")
+	}
 
 	// функция инициализации подключения
 	initializer := func(ch *amqp091.Channel) error {
 		log.Debug().Msg("init publishing worker")
+		options := loadOptions()
 
 		// запускаем функцию инициализации сразу после установки соединения, если такая функция задана
 		if options.init != nil {
@@ -38,29 +228,99 @@ func Publish(opts ...PublishOption) (Publisher, Initializer) {
 			}
 		}
 
+		// при публикации в несуществующую точку обмена сервер закрывает канал с ошибкой NOT_FOUND;
+		// часто это означает, что вместо точки обмена было указано название очереди;
+		// флаг immediate не поддерживается сервером и приводит к закрытию канала с ошибкой NOT_IMPLEMENTED
+		go func() {
+			err := <-ch.NotifyClose(make(chan *amqp091.Error, 1))
+			switch {
+			case err == nil:
+			case err.Code == amqp091.NotFound:
+				log.Warn().Str("reason", err.Reason).
+					Msg("publishing channel closed: use PublishToDefault to publish directly to a queue")
+			case err.Code == amqp091.NotImplemented && options.immediate:
+				log.Warn().Str("reason", err.Reason).
+					Msg("publishing channel closed: remove WithImmediate, the immediate flag is not supported")
+			}
+		}()
+
+		// включаем режим подтверждений публикации, если задана функция для их получения
+		// или требуется синхронное ожидание подтверждения
+		if options.confirmCallback != nil || options.confirm {
+			if err := ch.Confirm(false); err != nil {
+				log.Err(err).Msg("publishing confirm mode")
+				return err
+			}
+		}
+		var tracker *confirmTracker
+		if options.confirmCallback != nil {
+			tracker = &confirmTracker{callback: options.confirmCallback}
+			go tracker.listen(ch.NotifyPublish(make(chan amqp091.Confirmation)))
+		}
+		var waiter *confirmWaiter
+		if options.confirm {
+			var returns <-chan amqp091.Return
+			if options.mandatory {
+				returns = ch.NotifyReturn(make(chan amqp091.Return))
+			}
+			waiter = newConfirmWaiter()
+			go waiter.listen(ch.NotifyPublish(make(chan amqp091.Confirmation)), returns)
+		}
+
+		// отслеживаем приостановку приёма сообщений сервером: только в этом случае публикация может
+		// заблокироваться надолго
+		var blocked, paused int32
+		if conn := channelConnection(ch); conn != nil {
+			go func() {
+				for b := range conn.NotifyBlocked(make(chan amqp091.Blocking, 1)) {
+					atomic.StoreInt32(&blocked, boolToInt32(b.Active))
+					log.Debug().Bool("blocked", b.Active).Str("reason", b.Reason).Msg("publishing connection")
+				}
+			}()
+		}
+		go func() {
+			for active := range ch.NotifyFlow(make(chan bool, 1)) {
+				atomic.StoreInt32(&paused, boolToInt32(!active))
+			}
+		}()
+
 		// инициализируем функцию для публикации в канал с учётом всех опций
+		publish := channelPublisher(ch, options, tracker, waiter)
 		publishingFunc := func(ctx context.Context, exchange, key string, msg amqp091.Publishing) error {
-			return ch.PublishWithContext(ctx, exchange, key, options.mandatory, options.immediate, msg)
+			if atomic.LoadInt32(&blocked) == 1 || atomic.LoadInt32(&paused) == 1 {
+				return publishWithContext(ctx, publish, exchange, key, msg)
+			}
+			return publish(ctx, exchange, key, msg)
+		}
+		// сохраняем функцию для дальнейшего использования, если публикация не была закрыта
+		start := func() { storedPublishingFunc.Store(Publisher(publishingFunc)) }
+		if control := options.control; control != nil {
+			release := func() { storedPublishingFunc.Store(Publisher(closedPublisher)) }
+			if !control.attach(ch, start, release) {
+				log.Debug().Msg("publisher is closed")
+				return ch.Close()
+			}
+		} else {
+			start()
 		}
-		// сохраняем функцию для дальнейшего использования
-		storedPublishingFunc.Store(Publisher(publishingFunc))
 
 		return nil // больше ничего делать не нужно
 	}
 
 	// функция для публикации новых сообщений
 	publisher := func(ctx context.Context, exchange, key string, msg amqp091.Publishing) error {
-		log := log.Debug().Str("key", key)
+		options := loadOptions() // согласованные настройки на время публикации
+		event := log.Debug().Str("key", key)
 		if exchange != "" {
-			log = log.Str("exchange", exchange)
+			event = event.Str("exchange", exchange)
 		}
 		if msg.MessageId != "" {
-			log = log.Str("messageId", msg.MessageId)
+			event = event.Str("messageId", msg.MessageId)
 		}
-		log.Msg("publishing")
+		event.Msg("publishing")
 
 		publishingFunc := storedPublishingFunc.Load() // получаем функцию для публикации
-		if publishingFunc == nil {
+		if publishingFunc == nil && !options.dryRun {
 			return ErrNoChannel // функция не инициализирована
 		}
 
@@ -71,6 +331,9 @@ func Publish(opts ...PublishOption) (Publisher, Initializer) {
 			} else {
 				msg.ReplyTo = options.replyTo
 			}
+			if msg.ReplyTo != "" && options.replyTransform != nil {
+				msg.ReplyTo = options.replyTransform(msg.ReplyTo)
+			}
 		}
 
 		// добавляем временную метку, если это задано настройками
@@ -78,9 +341,26 @@ func Publish(opts ...PublishOption) (Publisher, Initializer) {
 			msg.Timestamp = time.Now()
 		}
 
-		// добавляем время жизни сообщения, если это задано
+		// добавляем время жизни сообщения, если это задано: сервер ожидает его в миллисекундах
 		if msg.Expiration == "" && options.ttl > 0 {
-			msg.Expiration = strconv.FormatInt(time.Now().Add(options.ttl).Unix(), 10)
+			msg.Expiration = strconv.FormatInt(options.ttl.Milliseconds(), 10)
+		}
+		if options.ttlWarning > 0 && msg.Expiration != "" {
+			if ms, err := strconv.ParseInt(msg.Expiration, 10, 64); err == nil &&
+				time.Duration(ms)*time.Millisecond < options.ttlWarning {
+				log.Warn().Str("key", key).Str("expiration", msg.Expiration).Dur("latency", options.ttlWarning).
+					Msg("message TTL is shorter than expected consume latency")
+			}
+		}
+
+		// задаём тип содержимого по умолчанию
+		if msg.ContentType == "" {
+			msg.ContentType = options.contentType
+		}
+
+		// задаём тип сообщения по умолчанию
+		if msg.Type == "" {
+			msg.Type = options.messageType
 		}
 
 		// задаём идентификатор приложения
@@ -88,12 +368,155 @@ func Publish(opts ...PublishOption) (Publisher, Initializer) {
 			msg.AppId = options.appID
 		}
 
+		// вызываем перехватчики в порядке регистрации: они могут изменить сообщение или отменить публикацию
+		for _, intercept := range options.interceptors {
+			if err := intercept(ctx, exchange, key, &msg); err != nil {
+				return err
+			}
+		}
+
+		// проверяем заголовки заранее, чтобы не получить ошибку при кодировании сообщения
+		if err := validateTable(msg.Headers); err != nil {
+			return err
+		}
+
+		// в тестовом режиме только записываем подготовленное сообщение в лог
+		if options.dryRun {
+			log.Info().Str("exchange", exchange).Str("key", key).Str("messageId", msg.MessageId).
+				Str("contentType", msg.ContentType).Int("size", len(msg.Body)).Interface("headers", msg.Headers).
+				Msg("dry-run: message not published")
+			return nil
+		}
+
+		// ограничиваем время публикации, если в контексте не задано своё
+		if _, ok := ctx.Deadline(); !ok && options.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, options.timeout)
+			defer cancel()
+		}
+
+		// объединяем одновременные публикации с одинаковым ключом
+		if options.singleFlight != nil {
+			if msgKey := options.singleFlight(msg); msgKey != "" {
+				return flights.do(ctx, flightKey(exchange, key, msgKey), func() error {
+					return publishingFunc.(Publisher)(ctx, exchange, key, msg)
+				})
+			}
+		}
+
 		return publishingFunc.(Publisher)(ctx, exchange, key, msg) // публикуем
 	}
 
 	return publisher, initializer
 }
 
+// closedPublisher используется в качестве функции публикации после её закрытия (смотри PublishControl.Close).
+func closedPublisher(context.Context, string, string, amqp091.Publishing) error {
+	return ErrNoChannel
+}
+
+// publishChannel описывает методы канала, используемые для публикации сообщений.
+type publishChannel interface {
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool,
+		msg amqp091.Publishing) error
+	PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool,
+		msg amqp091.Publishing) (*amqp091.DeferredConfirmation, error)
+	GetNextPublishSeqNo() uint64
+}
+
+// channelPublisher возвращает функцию публикации в канал с учётом всех опций. Каналы amqp091 не предназначены
+// для одновременной публикации, поэтому она выполняется последовательно.
+func channelPublisher(ch publishChannel, options publishOptions, tracker *confirmTracker,
+	waiter *confirmWaiter) Publisher {
+	var mu sync.Mutex
+	return func(ctx context.Context, exchange, key string, msg amqp091.Publishing) error {
+		mu.Lock()
+		if tracker == nil && waiter == nil {
+			defer mu.Unlock()
+			return publishError(ch.PublishWithContext(
+				ctx, exchange, key, options.mandatory, options.immediate, msg))
+		}
+
+		// регистрируем сообщение до публикации, чтобы не пропустить его подтверждение
+		var result <-chan error
+		tag := ch.GetNextPublishSeqNo()
+		if waiter != nil {
+			result = waiter.add(tag, exchange, key)
+		}
+		_, err := ch.PublishWithDeferredConfirmWithContext(
+			ctx, exchange, key, options.mandatory, options.immediate, msg)
+		if err == nil && tracker != nil {
+			tracker.publish(tag) // подтверждение придёт асинхронно
+		}
+		mu.Unlock()
+
+		if err != nil {
+			if waiter != nil {
+				waiter.remove(tag)
+			}
+			return publishError(err)
+		}
+		if result == nil {
+			return nil
+		}
+		if sink, ok := ctx.Value(confirmSinkKey{}).(func(<-chan error)); ok {
+			sink(result) // подтверждение ожидает вызывающий
+			return nil
+		}
+
+		// ожидаем подтверждения сервера вне блокировки, чтобы не мешать другим публикациям
+		select {
+		case err = <-result:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// confirmSinkKey используется как ключ контекста для функции, получающей результат подтверждения публикации.
+type confirmSinkKey struct{}
+
+// withConfirmSink возвращает контекст, при публикации с которым функция публикации не ожидает подтверждения
+// сервера, а передаёт канал с его результатом в функцию sink. Используется для пакетной публикации.
+func withConfirmSink(ctx context.Context, sink func(<-chan error)) context.Context {
+	return context.WithValue(ctx, confirmSinkKey{}, sink)
+}
+
+// publishWithContext вызывает функцию публикации с учётом отмены контекста. Используется, только пока сервер
+// приостановил приём сообщений.
+//
+// Библиотека amqp091-go не прерывает заблокированную публикацию, поэтому при отмене контекста ожидание
+// завершается с ошибкой контекста, а сама публикация продолжается в фоне до возобновления приёма сообщений
+// или закрытия канала. Следующие публикации в этот канал всё равно ожидают возобновления приёма, поэтому
+// оставленная публикация их дополнительно не задерживает. Для контекста без возможности отмены публикация
+// вызывается напрямую.
+func publishWithContext(ctx context.Context, pub Publisher, exchange, key string, msg amqp091.Publishing) error {
+	if ctx.Done() == nil {
+		return pub(ctx, exchange, key, msg)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- pub(ctx, exchange, key, msg) }()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// boolToInt32 преобразует логическое значение в число для хранения в атомарной переменной.
+func boolToInt32(v bool) int32 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
 // publishOptions описывает дополнительный параметры публикации.
 type publishOptions struct {
 	mandatory    bool
@@ -104,6 +527,19 @@ type publishOptions struct {
 	replyToQueue *Queue        // очередь для ответа
 	replyTo      string        // название очереди для ответа
 	ttl          time.Duration // время жизни сообщения
+
+	confirmCallback func(tag uint64, ack bool) // функция получения подтверждений
+	confirm         bool                       // синхронно ожидать подтверждения
+	contentType     string                     // тип содержимого по умолчанию
+	timeout         time.Duration              // ограничение времени публикации
+	interceptors    []PublishInterceptor       // обработчики сообщений перед публикацией
+	replyTransform  func(string) string        // преобразование названия очереди для ответа
+	messageType     string                     // тип сообщения по умолчанию
+	dryRun          bool                       // не отправлять сообщения на сервер
+
+	singleFlight func(amqp091.Publishing) string // ключ для объединения одновременных публикаций
+	ttlWarning   time.Duration                   // ожидаемое время до получения сообщения обработчиком
+	control      *PublishControl                 // управление настройками во время работы
 }
 
 // getOptions возвращает настройки после применения всех изменений.
@@ -115,12 +551,16 @@ func getPublishOpts(opts []PublishOption) publishOptions {
 	return options
 }
 
-// PublishOption изменяет настройки публикации сообщений.
-type PublishOption interface{ apply(*publishOptions) }
+// PublishOption изменяет настройки публикации сообщений. Может использоваться и как опция WorkWithOptions.
+type PublishOption interface {
+	apply(*publishOptions)
+	WorkOption
+}
 
 type funcPublishOption struct{ f func(*publishOptions) }
 
-func (fco *funcPublishOption) apply(co *publishOptions) { fco.f(co) }
+func (fco *funcPublishOption) apply(co *publishOptions)  { fco.f(co) }
+func (fco *funcPublishOption) applyWork(wo *workOptions) { wo.publish = append(wo.publish, fco) }
 
 func newFuncPublishOption(f func(*publishOptions)) *funcPublishOption {
 	return &funcPublishOption{f: f}
@@ -130,6 +570,11 @@ func WithMandatory() PublishOption {
 	return newFuncPublishOption(func(c *publishOptions) { c.mandatory = true })
 }
 
+// WithImmediate взводит флаг immediate при публикации сообщений.
+//
+// Deprecated: RabbitMQ начиная с версии 3.0 не поддерживает этот флаг и закрывает канал с ошибкой
+// NOT_IMPLEMENTED при первой же такой публикации. Для обнаружения недоставленных сообщений используйте
+// WithMandatory совместно с WithConfirm, а для ограничения времени ожидания в очереди — WithTTL.
 func WithImmediate() PublishOption {
 	return newFuncPublishOption(func(c *publishOptions) { c.immediate = true })
 }
@@ -155,6 +600,13 @@ func WithReplyToQueue(v *Queue) PublishOption {
 	return newFuncPublishOption(func(c *publishOptions) { c.replyToQueue = v })
 }
 
+// WithReplyTransform задаёт функцию преобразования названия очереди для ответа, заданного через WithReplyTo
+// или WithReplyToQueue, перед его добавлением в сообщение. Например, это позволяет добавить к названию префикс.
+// Значение ReplyTo, явно заданное в сообщении, не изменяется.
+func WithReplyTransform(v func(name string) string) PublishOption {
+	return newFuncPublishOption(func(c *publishOptions) { c.replyTransform = v })
+}
+
 // WithTimestamp добавляет временную метку перед отправкой сообщения, если она не задана.
 func WithTimestamp() PublishOption {
 	return newFuncPublishOption(func(c *publishOptions) { c.timestamp = true })
@@ -165,7 +617,78 @@ func WithInit(v Initializer) PublishOption {
 	return newFuncPublishOption(func(c *publishOptions) { c.init = v })
 }
 
-// WithTTL задаёт ограничение по времени жизни сообщения.
+// WithTTL задаёт ограничение по времени жизни сообщения в очереди, если оно не задано в самом сообщении.
+// Значение округляется до миллисекунд и записывается в поле Expiration, где его можно получить
+// в перехватчике публикации (смотри WithPublishInterceptor).
+//
+// Подтверждение публикации (WithConfirm) означает только приём сообщения сервером: сообщение, время жизни
+// которого истекло до получения обработчиком, удаляется или пересылается в dead-letter уже после подтверждения.
 func WithTTL(v time.Duration) PublishOption {
 	return newFuncPublishOption(func(c *publishOptions) { c.ttl = v })
 }
+
+// WithConfirmCallback включает режим подтверждения публикации сообщений сервером. Публикация не ожидает
+// подтверждения, а функция вызывается асинхронно для каждого сообщения с его номером и признаком успешного приёма.
+//
+// Номера сообщений начинаются с 1 для каждого нового канала, в том числе после переподключения, и идут
+// в порядке публикации. При закрытии канала все неподтверждённые сообщения считаются отклонёнными.
+// Функция вызывается из обработчика соединения, поэтому не должна блокировать выполнение.
+func WithConfirmCallback(v func(tag uint64, ack bool)) PublishOption {
+	return newFuncPublishOption(func(c *publishOptions) { c.confirmCallback = v })
+}
+
+// WithTTLWarning включает предупреждение в логе при публикации сообщения, время жизни которого меньше
+// ожидаемого времени до его получения обработчиком latency: такое сообщение, скорее всего, будет удалено
+// из очереди необработанным, хотя его публикация и будет подтверждена.
+func WithTTLWarning(latency time.Duration) PublishOption {
+	return newFuncPublishOption(func(c *publishOptions) { c.ttlWarning = latency })
+}
+
+// WithConfirm включает режим подтверждения публикации сообщений сервером, при котором публикация
+// ожидает подтверждения приёма сообщения. Если сервер отказался принять сообщение, то возвращается ошибка ErrNacked.
+//
+// При совместном использовании с WithMandatory публикация возвращает ошибку ErrUnroutable, если сообщение
+// не попало ни в одну очередь. Это позволяет надёжно узнать, было ли сообщение куда-либо доставлено.
+// Ожидание подтверждения ограничивается контекстом публикации или опцией WithPublishTimeout.
+//
+// Подтверждение не гарантирует, что сообщение будет обработано: например, сообщение с истёкшим
+// временем жизни (WithTTL) удаляется из очереди, хотя его публикация была подтверждена.
+func WithConfirm() PublishOption {
+	return newFuncPublishOption(func(c *publishOptions) { c.confirm = true })
+}
+
+// WithDefaultContentType задаёт тип содержимого для отправляемых сообщений, если он не указан в сообщении.
+func WithDefaultContentType(v string) PublishOption {
+	return newFuncPublishOption(func(c *publishOptions) { c.contentType = v })
+}
+
+// WithMessageType задаёт тип для отправляемых сообщений (поле Type), если он не указан в сообщении.
+// Это позволяет получателям различать сообщения без разбора их содержимого.
+func WithMessageType(v string) PublishOption {
+	return newFuncPublishOption(func(c *publishOptions) { c.messageType = v })
+}
+
+// WithDryRun включает тестовый режим публикации: сообщения проходят всю подготовку с учётом настроек
+// и перехватчиков, но вместо отправки на сервер записываются в лог, а публикация возвращает nil.
+// Соединение с сервером в этом режиме для публикации не требуется.
+func WithDryRun() PublishOption {
+	return newFuncPublishOption(func(c *publishOptions) { c.dryRun = true })
+}
+
+// WithPublishTimeout ограничивает время публикации сообщения, если в переданном контексте не задано своё.
+// По истечении времени публикация возвращает ошибку context.DeadlineExceeded.
+func WithPublishTimeout(v time.Duration) PublishOption {
+	return newFuncPublishOption(func(c *publishOptions) { c.timeout = v })
+}
+
+// PublishInterceptor описывает функцию, вызываемую для каждого сообщения непосредственно перед публикацией.
+// Функция может изменить сообщение или отменить его публикацию, вернув ошибку.
+type PublishInterceptor = func(ctx context.Context, exchange, key string, msg *amqp091.Publishing) error
+
+// WithPublishInterceptor добавляет перехватчик сообщений перед публикацией, например, для добавления
+// заголовков трассировки или подписи сообщений. Перехватчики вызываются в порядке добавления после
+// применения остальных настроек публикации. Ошибка перехватчика прерывает публикацию и возвращается
+// из функции публикации.
+func WithPublishInterceptor(v PublishInterceptor) PublishOption {
+	return newFuncPublishOption(func(c *publishOptions) { c.interceptors = append(c.interceptors, v) })
+}
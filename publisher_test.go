@@ -0,0 +1,249 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// fakePublishChannel имитирует канал публикации, возвращая заданную ошибку.
+type fakePublishChannel struct {
+	err error
+	seq uint64
+}
+
+func (c *fakePublishChannel) PublishWithContext(context.Context, string, string, bool, bool,
+	amqp091.Publishing) error {
+	return c.err
+}
+
+func (c *fakePublishChannel) PublishWithDeferredConfirmWithContext(context.Context, string, string, bool, bool,
+	amqp091.Publishing) (*amqp091.DeferredConfirmation, error) {
+	if c.err == nil {
+		c.seq++
+	}
+	return nil, c.err
+}
+
+func (c *fakePublishChannel) GetNextPublishSeqNo() uint64 { return c.seq + 1 }
+
+func TestPublishChannelClosed(t *testing.T) {
+	ctx := context.Background()
+
+	// канал закрылся во время публикации без подтверждений и с ожиданием подтверждения
+	ch := &fakePublishChannel{err: amqp091.ErrClosed}
+	pub := channelPublisher(ch, publishOptions{}, nil, nil)
+	if err := pub(ctx, "", "test", amqp091.Publishing{}); !errors.Is(err, ErrChannelClosed) {
+		t.Errorf("got %v, want ErrChannelClosed", err)
+	}
+	pub = channelPublisher(ch, publishOptions{confirm: true}, nil, newConfirmWaiter())
+	if err := pub(ctx, "", "test", amqp091.Publishing{}); !errors.Is(err, ErrChannelClosed) {
+		t.Errorf("confirm mode: got %v, want ErrChannelClosed", err)
+	}
+
+	// канал закрылся во время ожидания подтверждения
+	ch = &fakePublishChannel{}
+	waiter := newConfirmWaiter()
+	confirms := make(chan amqp091.Confirmation)
+	go waiter.listen(confirms, nil)
+	pub = channelPublisher(ch, publishOptions{confirm: true}, nil, waiter)
+	result := make(chan error, 1)
+	go func() { result <- pub(ctx, "", "test", amqp091.Publishing{}) }()
+	for {
+		waiter.mu.Lock()
+		pending := len(waiter.pending)
+		waiter.mu.Unlock()
+		if pending > 0 {
+			break
+		}
+		runtime.Gosched()
+	}
+	close(confirms)
+	if err := <-result; !errors.Is(err, ErrChannelClosed) {
+		t.Errorf("waiting confirm: got %v, want ErrChannelClosed", err)
+	}
+
+	// остальные ошибки возвращаются без изменений
+	other := &amqp091.Error{Code: amqp091.NotFound}
+	pub = channelPublisher(&fakePublishChannel{err: other}, publishOptions{}, nil, nil)
+	if err := pub(ctx, "", "test", amqp091.Publishing{}); err != other {
+		t.Errorf("got %v, want original error", err)
+	}
+}
+
+func TestPublishInterceptorOrder(t *testing.T) {
+	var order []string
+	var got amqp091.Publishing
+	errVeto := errors.New("veto")
+	interceptor := func(name string) PublishInterceptor {
+		return func(_ context.Context, _, key string, msg *amqp091.Publishing) error {
+			order = append(order, name)
+			if key == name {
+				return errVeto
+			}
+			if msg.Headers == nil {
+				msg.Headers = amqp091.Table{}
+			}
+			msg.Headers[name] = len(order)
+			got = *msg
+			return nil
+		}
+	}
+	pub, _ := Publish(WithDryRun(),
+		WithPublishInterceptor(interceptor("first")),
+		WithPublishInterceptor(interceptor("second")),
+		WithPublishInterceptor(interceptor("third")))
+
+	if err := pub(context.Background(), "", "test", amqp091.Publishing{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 3 || order[0] != "first" || order[1] != "second" || order[2] != "third" {
+		t.Errorf("unexpected order: %v", order)
+	}
+	if got.Headers["first"] != 1 || got.Headers["second"] != 2 || got.Headers["third"] != 3 {
+		t.Errorf("unexpected headers: %v", got.Headers)
+	}
+
+	// ошибка перехватчика отменяет публикацию, и следующие перехватчики не вызываются
+	order = nil
+	if err := pub(context.Background(), "", "second", amqp091.Publishing{}); !errors.Is(err, errVeto) {
+		t.Errorf("got %v, want veto error", err)
+	}
+	if len(order) != 2 {
+		t.Errorf("interceptors called after veto: %v", order)
+	}
+}
+
+func TestPublishHeaders(t *testing.T) {
+	var got amqp091.Publishing
+	var gotKey string
+	pub := Publisher(func(_ context.Context, _, key string, msg amqp091.Publishing) error {
+		got, gotKey = msg, key
+		return nil
+	})
+
+	msg := amqp091.Publishing{Headers: amqp091.Table{"format": "pdf", "trace": "1"}}
+	err := pub.PublishHeaders(context.Background(), "docs", amqp091.Table{"format": "zip", "type": "report"}, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotKey != "" {
+		t.Errorf("routing key %q, want empty", gotKey)
+	}
+	if got.Headers["format"] != "zip" || got.Headers["type"] != "report" || got.Headers["trace"] != "1" {
+		t.Errorf("unexpected headers: %v", got.Headers)
+	}
+	if msg.Headers["format"] != "pdf" {
+		t.Error("original message headers modified")
+	}
+}
+
+func TestPublishDryRun(t *testing.T) {
+	var got amqp091.Publishing
+	pub, _ := Publish(WithDryRun(), WithAppID("test"),
+		WithPublishInterceptor(func(_ context.Context, _, _ string, msg *amqp091.Publishing) error {
+			got = *msg
+			return nil
+		}))
+	if err := pub(context.Background(), "", "test", amqp091.Publishing{}); err != nil {
+		t.Fatal(err)
+	}
+	if got.AppId != "test" {
+		t.Errorf("message not prepared: %+v", got)
+	}
+}
+
+func TestPublishTTL(t *testing.T) {
+	var got amqp091.Publishing
+	pub, _ := Publish(WithDryRun(), WithTTL(1500*time.Millisecond),
+		WithPublishInterceptor(func(_ context.Context, _, _ string, msg *amqp091.Publishing) error {
+			got = *msg
+			return nil
+		}))
+	if err := pub(context.Background(), "", "test", amqp091.Publishing{}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Expiration != "1500" {
+		t.Errorf("expiration %q, want 1500", got.Expiration)
+	}
+}
+
+func TestPublishTombstone(t *testing.T) {
+	var got amqp091.Publishing
+	pub := Publisher(func(_ context.Context, _, _ string, msg amqp091.Publishing) error {
+		got = msg
+		return nil
+	})
+	if err := pub.PublishTombstone(context.Background(), "events", "user.42"); err != nil {
+		t.Fatal(err)
+	}
+	if !IsTombstone(amqp091.Delivery{Headers: got.Headers, Body: got.Body}) {
+		t.Errorf("not a tombstone: %+v", got)
+	}
+	if IsTombstone(amqp091.Delivery{Body: []byte("data")}) {
+		t.Error("regular message is a tombstone")
+	}
+}
+
+func TestPublishManyConfirm(t *testing.T) {
+	waiter := newConfirmWaiter()
+	confirms := make(chan amqp091.Confirmation)
+	go waiter.listen(confirms, nil)
+	defer close(confirms)
+	pub := channelPublisher(&fakePublishChannel{}, publishOptions{confirm: true}, nil, waiter)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- pub.PublishMany(context.Background(), "", "test", make([]amqp091.Publishing, 3))
+	}()
+
+	// все сообщения публикуются, не дожидаясь подтверждения предыдущих
+	deadline := time.Now().Add(time.Second)
+	for {
+		waiter.mu.Lock()
+		pending := len(waiter.pending)
+		waiter.mu.Unlock()
+		if pending == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("published %d messages before confirms, want 3", pending)
+		}
+		runtime.Gosched()
+	}
+
+	confirms <- amqp091.Confirmation{DeliveryTag: 1, Ack: true}
+	confirms <- amqp091.Confirmation{DeliveryTag: 2, Ack: false}
+	confirms <- amqp091.Confirmation{DeliveryTag: 3, Ack: true}
+	err := <-result
+	var errs PublishErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Index != 1 || !errors.Is(err, ErrNacked) {
+		t.Errorf("got %v, want nack of message 1", err)
+	}
+}
+
+func TestPublishManyErrors(t *testing.T) {
+	pub := Publisher(func(_ context.Context, _, _ string, msg amqp091.Publishing) error {
+		if msg.MessageId == "bad" {
+			return ErrNoChannel
+		}
+		return nil
+	})
+
+	err := pub.PublishMany(context.Background(), "", "test",
+		[]amqp091.Publishing{{MessageId: "ok"}, {MessageId: "bad"}})
+	if !errors.Is(err, ErrNoChannel) {
+		t.Errorf("errors.Is: %v does not match ErrNoChannel", err)
+	}
+	var msgErr *PublishError
+	if !errors.As(err, &msgErr) || msgErr.Index != 1 {
+		t.Errorf("errors.As: got %v", msgErr)
+	}
+	if errors.Is(err, ErrChannelClosed) {
+		t.Error("unexpected match with ErrChannelClosed")
+	}
+}
@@ -0,0 +1,36 @@
+package rabbitmq
+
+import (
+	"context"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Purge удаляет из очереди все сообщения, ожидающие доставки, и возвращает их количество.
+// Сообщения, уже переданные обработчикам, но ещё не подтверждённые, не удаляются.
+func (q *Queue) Purge(ch *amqp091.Channel) (int, error) {
+	removed, err := ch.QueuePurge(q.String(), false)
+	if err != nil {
+		return 0, err
+	}
+	log.Debug().Stringer("queue", q).Int("removed", removed).Msg("queue purge")
+	return removed, nil
+}
+
+// PurgeQueue подключается к серверу, удаляет все сообщения из очереди с указанным именем и возвращает
+// их количество. Предназначена для обслуживания и скриптов: внутри обработчиков используйте Queue.Purge.
+func PurgeQueue(ctx context.Context, addr, queue string) (int, error) {
+	conn, err := connect(ctx, addr)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	return NewQueue(queue).Purge(ch)
+}
@@ -1,6 +1,12 @@
 package rabbitmq
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/rabbitmq/amqp091-go"
 )
 
@@ -9,17 +15,80 @@ type Queue struct {
 	Name       string        // название очереди (пустое для приватной)
 	Durable    bool          // сохранять сообщения при перезагрузке
 	AutoDelete bool          // автоматическое удаление очереди при отключении
-	Exclusive  bool          // эксклюзивный доступ для текущего соединения
-	NoWait     bool          // не ждать подтверждения декларирования от сервера
+	Exclusive  bool          // очередь принадлежит текущему соединению и удаляется при его закрытии
+	NoWait     bool          // не ждать подтверждения декларирования от сервера (только для именованных)
+	Passive    bool          // только проверить существование очереди, не создавая её
 	Args       amqp091.Table // дополнительные параметры
 	queue      string        // название сгенерированной очереди
+	onNamed    func(string)  // вызывается при изменении названия очереди
+}
+
+// ErrNoWaitServerNamed возвращается при декларации очереди с пустым именем и флагом NoWait:
+// в этом случае сервер не возвращает сгенерированное имя очереди и использовать её невозможно.
+var ErrNoWaitServerNamed = errors.New("queue with server-generated name can't be declared with NoWait")
+
+// ErrLazyQueueType возвращается при декларации очереди в ленивом режиме с типом quorum или stream,
+// которые этот режим не поддерживают.
+var ErrLazyQueueType = errors.New("lazy mode is supported only by classic queues")
+
+// ErrQueueMismatch возвращается, если очередь уже существует на сервере с другими параметрами.
+// Подробности расхождения можно получить через QueueMismatchError.
+var ErrQueueMismatch = errors.New("queue mismatch")
+
+// QueueMismatchError описывает ошибку декларации очереди, которая уже существует на сервере
+// с другими параметрами (PRECONDITION_FAILED).
+type QueueMismatchError struct {
+	Queue  string         // название очереди
+	Detail string         // описание расхождения, полученное от сервера
+	Err    *amqp091.Error // исходная ошибка сервера
+}
+
+func (e *QueueMismatchError) Error() string {
+	return fmt.Sprintf("queue %q mismatch: %s", e.Queue, e.Detail)
 }
 
+func (e *QueueMismatchError) Unwrap() error        { return e.Err }
+func (e *QueueMismatchError) Is(target error) bool { return target == ErrQueueMismatch }
+
 // NewQueue возвращает новое описание очереди с заданным именем.
 func NewQueue(name string) *Queue {
 	return &Queue{Name: name}
 }
 
+// NewPrivateQueue возвращает описание приватной очереди соединения: эксклюзивной, автоматически удаляемой
+// и с именем, которое генерирует сервер. Обычно такая очередь используется для получения ответов.
+//
+// Имя очереди становится известно только после её декларации (смотри String) и действительно только в рамках
+// текущего соединения: после переподключения сервер сгенерирует для очереди новое имя.
+func NewPrivateQueue() *Queue {
+	return &Queue{Exclusive: true, AutoDelete: true}
+}
+
+// NewExclusiveQueue возвращает описание эксклюзивной очереди с заданным именем. Такая очередь принадлежит
+// соединению, в котором она была задекларирована: другие соединения не могут ни получать из неё сообщения,
+// ни декларировать её, а при закрытии соединения очередь удаляется сервером.
+//
+// Если требуется только единственный обработчик для общей очереди, то используйте обычную очередь
+// с опцией WithExclusiveConsumer.
+func NewExclusiveQueue(name string) *Queue {
+	return &Queue{Name: name, Exclusive: true}
+}
+
+// RetryQueue возвращает описание долговременной очереди для отложенной повторной обработки сообщений.
+// Сообщения хранятся в ней заданное время, после чего пересылаются в точку обмена dlx с ключом маршрутизации dlKey.
+// Обычно такая очередь не имеет обработчиков, а сообщения возвращаются в исходную очередь по истечении времени.
+func RetryQueue(name string, ttl time.Duration, dlx, dlKey string) *Queue {
+	return &Queue{
+		Name:    name,
+		Durable: true,
+		Args: amqp091.Table{
+			"x-message-ttl":             ttl.Milliseconds(),
+			"x-dead-letter-exchange":    dlx,
+			"x-dead-letter-routing-key": dlKey,
+		},
+	}
+}
+
 // String возвращает имя очереди. Возвращаемое значение может отличаться от Name.
 // Если очередь была с пустым именем и прошла декларацию, то возвращаемое название очереди сгенерировано сервером.
 func (q *Queue) String() string {
@@ -33,23 +102,147 @@ func (q *Queue) String() string {
 // declare декларирует очередь для канала соединения с RabbitMQ.
 //
 // Сохраняет возвращенное сервером название очереди, которое потом можно получить через метод String.
+// Очередь с пустым именем при каждой декларации получает от сервера новое имя: повторно задекларировать очередь
+// со сгенерированным именем нельзя, так как сервер резервирует префикс «amq.».
 // Если возвращается ошибка, то декларация не прошла и канал после этого не действителен.
+//
+// С флагом NoWait сервер не присылает ответ, поэтому ошибки декларации не возвращаются, а приводят
+// к асинхронному закрытию канала. По этой же причине NoWait не допускается для очередей с пустым именем.
 func (q *Queue) declare(ch *amqp091.Channel) error {
-	queue, err := ch.QueueDeclare(
-		q.String(),   // name
+	return q.declareMode(ch, q.Passive)
+}
+
+// declareMode декларирует очередь так же, как declare, но пассивность декларации задаётся явно.
+func (q *Queue) declareMode(ch *amqp091.Channel, passive bool) error {
+	if err := q.validate(); err != nil {
+		return err
+	}
+
+	name := q.Name
+	declare := ch.QueueDeclare
+	if passive {
+		declare = ch.QueueDeclarePassive
+	}
+	queue, err := declare(
+		name,         // name
 		q.Durable,    // durable
 		q.AutoDelete, // delete when unused
 		q.Exclusive,  // exclusive
 		q.NoWait,     // noWait
 		q.Args,       // arguments
 	)
-	q.queue = queue.Name // сохраняем имя инициализированной очереди
+	if !q.NoWait && err == nil && queue.Name != q.queue {
+		q.queue = queue.Name // сохраняем имя инициализированной очереди
+		if q.onNamed != nil {
+			q.onNamed(queue.Name)
+		}
+	}
 
 	log.Debug().Str("module", "rabbitmq").Str("queue", queue.Name).Msg("queue declare")
-	return err
+	return queueError(name, err)
+}
+
+// DeclareIfAbsent возвращает инициализатор, который создаёт очередь, только если она ещё не существует.
+// Сначала выполняется пассивная декларация, которая не проверяет параметры существующей очереди, поэтому
+// расхождение с параметрами, заданными другим сервисом, не приводит к ошибке PRECONDITION_FAILED. Только если
+// очереди нет, она декларируется с заданными параметрами.
+//
+// Отсутствие очереди сервер сообщает закрытием канала, поэтому в этом случае инициализатор возвращает ошибку,
+// по которой Run повторяет инициализацию на новом канале, где очередь уже создаётся. Поэтому инициализатор
+// следует запускать через Run, объединив его с обработчиком очереди в Chain.
+//
+// Обработчик перед получением сообщений сам декларирует очередь с заданными параметрами, поэтому для него
+// нужно использовать копию описания очереди с флагом Passive: иначе расхождение параметров всё равно приведёт
+// к ошибке. По той же причине не подходит опция WithPreStart: она вызывается уже после декларации очереди.
+func (q *Queue) DeclareIfAbsent() Initializer {
+	return declareIfAbsent(q.Name, q.declareMode)
+}
+
+// declareIfAbsent возвращает инициализатор DeclareIfAbsent с указанной функцией декларации. Признак отсутствия
+// очереди хранится в инициализаторе и используется только при его следующем вызове.
+func declareIfAbsent(name string, declare func(ch *amqp091.Channel, passive bool) error) Initializer {
+	var absent bool // очередь не найдена на предыдущем канале
+	return func(ch *amqp091.Channel) error {
+		if absent {
+			absent = false
+			return declare(ch, false)
+		}
+
+		err := declare(ch, true)
+		var amqpErr *amqp091.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp091.NotFound {
+			absent = true
+			log.Debug().Str("queue", name).Msg("queue not found: declare on a new channel")
+			return &retryError{err: err}
+		}
+		return err
+	}
+}
+
+// queueError преобразует ошибку сервера о несовпадении параметров очереди в QueueMismatchError.
+// Остальные ошибки возвращаются без изменений.
+func queueError(name string, err error) error {
+	var amqpErr *amqp091.Error
+	if !errors.As(err, &amqpErr) || amqpErr.Code != amqp091.PreconditionFailed {
+		return err
+	}
+
+	return &QueueMismatchError{
+		Queue:  name,
+		Detail: strings.TrimPrefix(amqpErr.Reason, "PRECONDITION_FAILED - "),
+		Err:    amqpErr,
+	}
+}
+
+// validate проверяет допустимость сочетания параметров очереди.
+func (q *Queue) validate() error {
+	if q.Name == "" && q.NoWait {
+		return ErrNoWaitServerNamed
+	}
+	if err := validateTable(q.Args); err != nil {
+		return err
+	}
+	if q.Exclusive && q.Durable {
+		log.Warn().Str("queue", q.Name).Msg("durable exclusive queue is deleted with its connection anyway")
+	}
+	if q.Args["x-queue-mode"] == "lazy" {
+		if kind := q.Args["x-queue-type"]; kind == "quorum" || kind == "stream" {
+			return ErrLazyQueueType
+		}
+	}
+	return nil
+}
+
+// WithLazy включает для очереди ленивый режим (x-queue-mode: lazy), при котором сообщения сразу сохраняются
+// на диск, что снижает расход памяти при большом количестве накопленных сообщений. Возвращает саму очередь.
+//
+// Режим поддерживается только классическими очередями. Начиная с RabbitMQ 3.12 он игнорируется,
+// а вместо него рекомендуется использовать quorum или stream очереди.
+func (q *Queue) WithLazy() *Queue {
+	if q.Args == nil {
+		q.Args = make(amqp091.Table)
+	}
+	q.Args["x-queue-mode"] = "lazy"
+	return q
+}
+
+// OnNamed задаёт функцию, которая вызывается после декларации очереди, если её название изменилось.
+// Для очереди с пустым именем это происходит при каждой декларации, в том числе после переподключения,
+// когда сервер генерирует новое имя. Это позволяет, например, сообщить другим сервисам новый адрес
+// очереди для ответов. Возвращает саму очередь.
+//
+// Функция вызывается из инициализатора канала, поэтому не должна надолго блокировать выполнение.
+func (q *Queue) OnNamed(f func(name string)) *Queue {
+	q.onNamed = f
+	return q
 }
 
 // Consume возвращает инициализированный обработчик входящих сообщений данной очереди.
 func (q *Queue) Consume(handler func(amqp091.Delivery), opts ...ConsumeOption) Initializer {
 	return Consume(q, handler, opts...)
 }
+
+// ConsumeCtx возвращает инициализированный обработчик входящих сообщений данной очереди с поддержкой контекста.
+func (q *Queue) ConsumeCtx(ctx context.Context, handler CtxHandler, opts ...ConsumeOption) Initializer {
+	return ConsumeCtx(ctx, q, handler, opts...)
+}
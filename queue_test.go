@@ -0,0 +1,106 @@
+package rabbitmq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestQueueNoWait(t *testing.T) {
+	tests := []struct {
+		name   string
+		noWait bool
+		err    error
+	}{
+		{"", false, nil},
+		{"", true, ErrNoWaitServerNamed},
+		{"test.queue", false, nil},
+		{"test.queue", true, nil},
+	}
+	for _, test := range tests {
+		q := &Queue{Name: test.name, NoWait: test.noWait}
+		if err := q.validate(); !errors.Is(err, test.err) {
+			t.Errorf("name %q, noWait %v: got %v, want %v", test.name, test.noWait, err, test.err)
+		}
+	}
+}
+
+func TestQueueMismatch(t *testing.T) {
+	amqpErr := &amqp091.Error{
+		Code:   amqp091.PreconditionFailed,
+		Reason: "PRECONDITION_FAILED - inequivalent arg 'x-message-ttl' for queue 'test.queue' in vhost '/'",
+	}
+	err := queueError("test.queue", amqpErr)
+	if !errors.Is(err, ErrQueueMismatch) {
+		t.Fatalf("got %v, want ErrQueueMismatch", err)
+	}
+
+	var mismatch *QueueMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("got %T, want *QueueMismatchError", err)
+	}
+	if mismatch.Queue != "test.queue" {
+		t.Errorf("queue: got %q", mismatch.Queue)
+	}
+	const detail = "inequivalent arg 'x-message-ttl' for queue 'test.queue' in vhost '/'"
+	if mismatch.Detail != detail {
+		t.Errorf("detail: got %q, want %q", mismatch.Detail, detail)
+	}
+	if !errors.Is(err, amqpErr) {
+		t.Error("original error is not wrapped")
+	}
+
+	other := &amqp091.Error{Code: amqp091.NotFound}
+	if err := queueError("test.queue", other); err != other {
+		t.Errorf("got %v, want unchanged error", err)
+	}
+}
+
+func TestQueueLazy(t *testing.T) {
+	for kind, want := range map[string]error{
+		"classic": nil,
+		"quorum":  ErrLazyQueueType,
+		"stream":  ErrLazyQueueType,
+	} {
+		q := NewQueue("test.queue").WithLazy()
+		q.Args["x-queue-type"] = kind
+		if err := q.validate(); !errors.Is(err, want) {
+			t.Errorf("%s: got %v, want %v", kind, err, want)
+		}
+	}
+}
+
+func TestQueueDeclareIfAbsent(t *testing.T) {
+	var calls []bool
+	exists := false
+	declare := func(_ *amqp091.Channel, passive bool) error {
+		calls = append(calls, passive)
+		if passive && !exists {
+			return &amqp091.Error{Code: amqp091.NotFound}
+		}
+		return nil
+	}
+
+	// очереди нет: пассивная декларация, повтор на новом канале и создание очереди
+	init := declareIfAbsent("test.queue", declare)
+	var retry *retryError
+	if err := init(nil); !errors.As(err, &retry) {
+		t.Fatalf("got %v, want retry", err)
+	}
+	if err := init(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || !calls[0] || calls[1] {
+		t.Errorf("absent queue: unexpected declarations %v", calls)
+	}
+
+	// очередь существует: только пассивная декларация
+	calls, exists = nil, true
+	if err := init(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || !calls[0] {
+		t.Errorf("existing queue: unexpected declarations %v", calls)
+	}
+}
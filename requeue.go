@@ -0,0 +1,61 @@
+package rabbitmq
+
+import (
+	"context"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// RetryCountHeader задаёт заголовок сообщения со счётчиком повторных публикаций через RequeueAtTail.
+const RetryCountHeader = "x-retry-count"
+
+// RetryCount возвращает количество повторных публикаций сообщения через RequeueAtTail.
+func RetryCount(d amqp091.Delivery) int {
+	switch count := d.Headers[RetryCountHeader].(type) {
+	case int64:
+		return int(count)
+	case int32:
+		return int(count)
+	case int:
+		return count
+	}
+	return 0
+}
+
+// RequeueAtTail повторно публикует полученное сообщение в конец очереди queue через точку обмена по умолчанию
+// и подтверждает приём исходного. В отличие от Nack с возвратом в очередь, который помещает сообщение
+// в её начало и приводит к немедленной повторной обработке, это позволяет сначала обработать остальные сообщения.
+//
+// Свойства и заголовки сообщения сохраняются, а счётчик в заголовке RetryCountHeader увеличивается на единицу.
+// Если повторная публикация не удалась, то исходное сообщение не подтверждается и возвращается ошибка.
+// Предназначена для обработчиков с ручным подтверждением приёма сообщений (WithNoAutoAck).
+func RequeueAtTail(ctx context.Context, pub Publisher, queue string, d amqp091.Delivery) error {
+	headers := make(amqp091.Table, len(d.Headers)+1)
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[RetryCountHeader] = int64(RetryCount(d) + 1)
+
+	msg := amqp091.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    d.DeliveryMode,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		Expiration:      d.Expiration,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		UserId:          d.UserId,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+	if err := pub.PublishToDefault(ctx, queue, msg); err != nil {
+		return err
+	}
+
+	log.Debug().Str("queue", queue).Str("messageId", d.MessageId).Msg("message requeued at tail")
+	return d.Ack(false)
+}
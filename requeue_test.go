@@ -0,0 +1,43 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+type ackRecorder struct{ acked, nacked int }
+
+func (a *ackRecorder) Ack(uint64, bool) error        { a.acked++; return nil }
+func (a *ackRecorder) Nack(uint64, bool, bool) error { a.nacked++; return nil }
+func (a *ackRecorder) Reject(uint64, bool) error     { a.nacked++; return nil }
+
+func TestRequeueAtTail(t *testing.T) {
+	var got amqp091.Publishing
+	var gotKey string
+	pub := Publisher(func(_ context.Context, _, key string, msg amqp091.Publishing) error {
+		got, gotKey = msg, key
+		return nil
+	})
+
+	ack := new(ackRecorder)
+	d := amqp091.Delivery{
+		Acknowledger:  ack,
+		CorrelationId: "42",
+		Headers:       amqp091.Table{"trace": "1", RetryCountHeader: int32(2)},
+		Body:          []byte("body"),
+	}
+	if err := RequeueAtTail(context.Background(), pub, "tasks", d); err != nil {
+		t.Fatal(err)
+	}
+	if gotKey != "tasks" || got.CorrelationId != "42" || string(got.Body) != "body" || got.Headers["trace"] != "1" {
+		t.Errorf("unexpected message: %q %+v", gotKey, got)
+	}
+	if count := RetryCount(amqp091.Delivery{Headers: got.Headers}); count != 3 {
+		t.Errorf("retry count %d, want 3", count)
+	}
+	if ack.acked != 1 {
+		t.Errorf("original acked %d times", ack.acked)
+	}
+}
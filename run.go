@@ -2,37 +2,123 @@ package rabbitmq
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/rand"
 	"sync"
+	"time"
 
 	"github.com/rabbitmq/amqp091-go"
 )
 
+// Параметры задержки между переподключениями в Run, если соединение разрывается вскоре после установки,
+// например, из-за ошибок авторизации или инициализации обработчиков.
+var (
+	MinUptime         = time.Second * 30 // соединение, проработавшее меньше, считается неудачным
+	MaxReconnectDelay = time.Minute      // максимальная задержка перед повторным соединением
+)
+
+// ShutdownTimeout задаёт максимальное время ожидания завершения обработки уже полученных сообщений
+// при плановой остановке Run. По истечении этого времени соединение закрывается, а подтвердить приём
+// ещё обрабатываемых сообщений уже не получится: сервер доставит их повторно.
+var ShutdownTimeout = time.Second * 30
+
+// PermanentErrorCodes задаёт коды ошибок сервера, возникающих при инициализации обработчиков, после которых
+// Run не переподключается, а завершается с ошибкой: повторная инициализация всё равно не будет успешной.
+// По умолчанию это несовпадение параметров (PRECONDITION_FAILED) и отсутствие прав доступа (ACCESS_REFUSED).
+var PermanentErrorCodes = []int{amqp091.PreconditionFailed, amqp091.AccessRefused}
+
+// IsPermanentInitError возвращает true, если ошибка инициализации обработчика не исчезнет при повторной
+// попытке: это ошибки сервера с кодами из PermanentErrorCodes и ошибки недопустимых параметров очереди
+// или обработчика.
+func IsPermanentInitError(err error) bool {
+	if errors.Is(err, ErrQueueMismatch) || errors.Is(err, ErrNoWaitServerNamed) || errors.Is(err, ErrLazyQueueType) ||
+		errors.Is(err, ErrInvalidTable) {
+		return true
+	}
+	var amqpErr *amqp091.Error
+	if !errors.As(err, &amqpErr) {
+		return false
+	}
+	for _, code := range PermanentErrorCodes {
+		if amqpErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
 // Initializer является синонимом функции для инициализации канала соединения RabbitMQ.
 type Initializer = func(*amqp091.Channel) error
 
+// Chain объединяет несколько инициализаторов в один, который выполняет их по очереди на одном канале.
+// Выполнение прерывается на первой ошибке, и она возвращается. Это позволяет, например, задекларировать
+// точку обмена и связанные с ней очереди на том же канале, на котором затем запускается обработчик.
+func Chain(inits ...Initializer) Initializer {
+	return func(ch *amqp091.Channel) error {
+		for _, init := range inits {
+			if err := init(ch); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
 // Run осуществляет подключение к серверу RabbitMQ и инициализирует обработчики с этим соединением.
 // Для каждого обработчика создаётся отдельный канал, а в случае ошибки инициализации всё повторяется.
 //
-// Возвращает ошибку, если превышено количество попыток установки соединений.
-// Плановое завершение осуществляется через контекст.
+// Возвращает ошибку, если превышено количество попыток установки соединений. При отрицательном значении
+// MaxIteration попытки соединения не ограничены и Run завершается только по контексту.
+// Плановое завершение осуществляется через контекст. При этом получение новых сообщений отменяется, и Run
+// закрывает соединение и возвращает управление только после того, как обработчики входящих сообщений завершат
+// обработку уже полученных сообщений, но не дольше ShutdownTimeout.
+//
+// Если соединение разрывается раньше, чем через MinUptime после установки, то перед повторным подключением
+// делается задержка, которая удваивается при каждом таком разрыве (начиная с ReconnectDelay и не больше
+// MaxReconnectDelay) и сбрасывается после достаточно долгой работы соединения.
+//
+// Если ошибка инициализации обработчика является постоянной (смотри IsPermanentInitError), то Run
+// не переподключается, а сразу возвращает эту ошибку.
 func Run(ctx context.Context, addr string, initializers ...Initializer) error {
+	var backoff runBackoff
 	for {
-		conn, err := Connect(addr) // подключаемся к серверу
+		conn, err := connect(ctx, addr) // подключаемся к серверу
+		if ctx.Err() != nil {
+			if conn != nil {
+				conn.Close()
+			}
+			log.Debug().Str("reason", ctx.Err().Error()).Msg("stopped")
+			return nil // плановая остановка во время подключения
+		}
 		if err != nil {
 			return err // ошибка установки соединения
 		}
+		started := time.Now()
 
 		// запускаем зарегистрированные для данного соединения обработчики
+		channels := make([]*amqp091.Channel, 0, len(initializers))
 		for _, init := range initializers {
+			// для каждого сервиса создаём отдельный канал и инициализируем на нём обработчик
 			var ch *amqp091.Channel
-			ch, err = conn.Channel() // для каждого сервиса создаём отдельный канал
-			if err != nil {
+			if ch, err = initChannel(ctx, conn, init); err != nil {
 				break
 			}
-			// инициализируем обработчик сервиса на заданном канале
-			if err = init(ch); err != nil {
+			channels = append(channels, ch)
+		}
+		// при ошибке сразу закрываем уже инициализированные каналы; их обработчики завершились бы и так,
+		// так как закрытие соединения закрывает все его каналы, но каналы освобождаются раньше
+		if err != nil {
+			for _, ch := range channels {
 				ch.Close()
-				break
+			}
+			if IsPermanentInitError(err) {
+				for _, ch := range channels {
+					releaseChannel(ch) // удаляем зарегистрированные для каналов функции
+				}
+				conn.Close()
+				log.Err(err).Msg("permanent initialization error")
+				return err // повторная инициализация не поможет
 			}
 		}
 
@@ -43,18 +129,101 @@ func Run(ctx context.Context, addr string, initializers ...Initializer) error {
 			case err = <-conn.NotifyClose(make(chan *amqp091.Error)):
 				log.Err(err).Msg("connection closed")
 			case <-ctx.Done(): // плановое завершение
+				for _, ch := range channels {
+					shutdown(ch) // соединение ещё открыто
+				}
 			}
 		}
+		waits := make([]func(), len(channels))
+		for i, ch := range channels {
+			waits[i] = releaseChannel(ch)
+		}
 
-		conn.Close()                      // закрываем соединение
 		if err := ctx.Err(); err != nil { // отслеживаем плановую остановку сервиса
+			// получение сообщений уже отменено: обработчики завершают обработку полученных сообщений,
+			// пока соединение открыто и их приём можно подтвердить
+			if !waitWorkers(waits, ShutdownTimeout) {
+				log.Warn().Dur("timeout", ShutdownTimeout).Msg("shutdown timeout: handlers are still running")
+			}
+			conn.Close()
 			log.Debug().Str("reason", err.Error()).Msg("stopped")
 			return nil
 		}
+		conn.Close() // закрываем соединение
+
+		// делаем паузу, если соединение разорвалось слишком быстро
+		if delay := backoff.next(time.Since(started)); delay > 0 {
+			log.Warn().Dur("delay", delay).Msg("connection closed shortly after start")
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				log.Debug().Str("reason", ctx.Err().Error()).Msg("stopped")
+				return nil
+			}
+		}
 		// осуществляем повторное соединение и инициализацию
 	}
 }
 
+// runBackoff вычисляет задержку перед повторным соединением в Run.
+type runBackoff struct {
+	delay time.Duration // последняя задержка без учёта случайного разброса
+}
+
+// next возвращает задержку перед повторным соединением с учётом времени работы предыдущего соединения.
+// Для долго проработавшего соединения задержка сбрасывается и возвращается 0. К задержке добавляется
+// случайный разброс, чтобы множество клиентов не переподключались к серверу одновременно.
+func (b *runBackoff) next(uptime time.Duration) time.Duration {
+	if uptime >= MinUptime {
+		b.delay = 0
+		return 0
+	}
+
+	switch {
+	case b.delay == 0:
+		b.delay = ReconnectDelay
+	case b.delay < MaxReconnectDelay:
+		b.delay *= 2
+	}
+	if b.delay > MaxReconnectDelay {
+		b.delay = MaxReconnectDelay
+	}
+	if b.delay <= 0 {
+		return 0
+	}
+
+	half := b.delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// initChannel создаёт новый канал соединения и инициализирует на нём обработчик.
+// Если обработчик запрашивает повтор, то после задержки инициализация повторяется на новом канале
+// без разрыва соединения.
+func initChannel(ctx context.Context, conn *amqp091.Connection, init Initializer) (*amqp091.Channel, error) {
+	for {
+		ch, err := conn.Channel()
+		if err != nil {
+			return nil, err
+		}
+		bindConnection(ch, conn)
+		if err = init(ch); err == nil {
+			return ch, nil
+		}
+		ch.Close()
+		releaseChannel(ch)
+
+		var retry *retryError
+		if !errors.As(err, &retry) {
+			return nil, err
+		}
+		select {
+		case <-time.After(retry.delay): // повторяем инициализацию на новом канале
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
 // Init запускает асинхронное выполнение Run и ожидает завершения самого первого процесса инициализации,
 // после чего возвращает управление. Возвращает ошибку, если при первой инициализации обработчиков или установке
 // соединения произошла ошибка.
@@ -87,15 +256,114 @@ func Init(ctx context.Context, addr string, workers ...Initializer) error {
 // к серверу RabbitMQ, очередь с входящими сообщениями и их обработчик. Кроме этого можно указать необязательные
 // параметры для публикации. Возвращает функцию для публикации новых сообщений.
 //
-// По умолчанию автоматически отсылается подтверждение о приёме входящих сообщений, а для исходящих заполняется
+// По умолчанию приём входящего сообщения подтверждается только после успешного завершения обработчика,
+// а если обработчик завершился паникой, то сообщение отклоняется (возвращается ли оно при этом в очередь,
+// задаётся через SetDefaultNackBehavior), а паника записывается в лог. Для исходящих сообщений заполняется
 // поле ReplyTo указанием на очередь входящих сообщений.
+//
+// Для задания параметров получения сообщений, например, WithNoAutoAck() для подтверждения сообщений самим
+// обработчиком или WithAutoAck() для прежнего автоматического подтверждения сразу при получении, используйте
+// WorkWithOptions.
 func Work(ctx context.Context, addr string, queue *Queue, handler Handler, opts ...PublishOption) (Publisher, error) {
-	consumerWorker := queue.Consume(handler)                         // обработка входящих сообщений
-	opts = append([]PublishOption{WithReplyToQueue(queue)}, opts...) // добавляем опцию публикации
-	pubFunc, pubWorker := Publish(opts...)                           // публикация новых
-	err := Init(ctx, addr, consumerWorker, pubWorker)                // запускаем подключение к серверу
+	workOpts := make([]WorkOption, len(opts))
+	for i, opt := range opts {
+		workOpts[i] = opt
+	}
+	return WorkWithOptions(ctx, addr, queue, handler, workOpts...)
+}
+
+// WorkWithOptions аналогична Work, но кроме параметров публикации (PublishOption) принимает и параметры
+// получения сообщений (ConsumeOption).
+func WorkWithOptions(ctx context.Context, addr string, queue *Queue, handler Handler,
+	opts ...WorkOption) (Publisher, error) {
+	options := getWorkOptions(opts)
+	handler, consumeOpts := workHandler(handler, options.consume)
+	consumerWorker := queue.Consume(handler, consumeOpts...)                        // обработка входящих сообщений
+	pubOpts := append([]PublishOption{WithReplyToQueue(queue)}, options.publish...) // добавляем опцию публикации
+	pubFunc, pubWorker := Publish(pubOpts...)                                       // публикация новых
+	err := Init(ctx, addr, consumerWorker, pubWorker)                               // запускаем подключение к серверу
 	if err != nil {
 		return nil, err
 	}
 	return pubFunc, nil // возвращаем функцию публикации
 }
+
+// workHandler возвращает обработчик и опции получения сообщений для WorkWithOptions. Если подтверждение сообщений
+// не задано явно, то приём сообщения подтверждается только после завершения обработчика.
+func workHandler(handler Handler, opts []ConsumeOption) (Handler, []ConsumeOption) {
+	if options := getConsumeOptions(opts); options.noAutoAck || options.autoAck {
+		return handler, opts
+	}
+	h := handler
+	handler = ackHandler(func(msg amqp091.Delivery) error { h(msg); return nil })
+	return handler, append(opts[:len(opts):len(opts)], WithNoAutoAck())
+}
+
+// ErrHandler описывает обработчик входящего сообщения, результат которого определяет подтверждение его приёма:
+// при успешной обработке сообщение подтверждается, а при ошибке отклоняется.
+type ErrHandler = func(amqp091.Delivery) error
+
+// WorkErr аналогична Work, но предназначена для надёжной обработки запросов: приём сообщения подтверждается
+// только после успешного завершения обработчика, а при ошибке сообщение отклоняется (возвращается ли оно при этом
+// в очередь, задаётся через SetDefaultNackBehavior). Опция WithNoAutoAck добавляется автоматически.
+//
+// Обработчик создаётся функцией newHandler, которой передаётся функция публикации, например, для отправки
+// ответов. Возвращает ту же функцию публикации.
+func WorkErr(ctx context.Context, addr string, queue *Queue, newHandler func(Publisher) ErrHandler,
+	opts ...WorkOption) (Publisher, error) {
+	options := getWorkOptions(opts)
+	pubOpts := append([]PublishOption{WithReplyToQueue(queue)}, options.publish...)
+	pubFunc, pubWorker := Publish(pubOpts...)                // публикация новых
+	handler := ackHandler(newHandler(pubFunc))               // обработчик с подтверждением по результату
+	consumeOpts := append(options.consume, WithNoAutoAck())  // подтверждаем сообщения сами
+	consumerWorker := queue.Consume(handler, consumeOpts...) // обработка входящих сообщений
+	if err := Init(ctx, addr, consumerWorker, pubWorker); err != nil {
+		return nil, err
+	}
+	return pubFunc, nil
+}
+
+// ackHandler возвращает обработчик, подтверждающий или отклоняющий сообщение по результату обработки.
+// Паника обработчика перехватывается и считается ошибкой обработки.
+func ackHandler(handler ErrHandler) Handler {
+	return func(msg amqp091.Delivery) {
+		var err error
+		if err = callHandler(handler, msg); err == nil {
+			err = msg.Ack(false)
+		} else {
+			log.Err(err).Str("key", msg.RoutingKey).Msg("handler")
+			err = msg.Nack(false, nackRequeue)
+		}
+		if err != nil {
+			log.Err(err).Msg("acknowledgement")
+		}
+	}
+}
+
+// callHandler вызывает обработчик сообщения, преобразуя его панику в ошибку.
+func callHandler(handler ErrHandler, msg amqp091.Delivery) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return handler(msg)
+}
+
+// workOptions описывает параметры WorkWithOptions, разделённые на публикацию и получение сообщений.
+type workOptions struct {
+	publish []PublishOption
+	consume []ConsumeOption
+}
+
+// getWorkOptions разделяет опции WorkWithOptions на опции публикации и получения сообщений.
+func getWorkOptions(opts []WorkOption) workOptions {
+	var options workOptions
+	for _, opt := range opts {
+		opt.applyWork(&options)
+	}
+	return options
+}
+
+// WorkOption изменяет настройки WorkWithOptions. В качестве опций используются PublishOption и ConsumeOption.
+type WorkOption interface{ applyWork(*workOptions) }
@@ -0,0 +1,120 @@
+package rabbitmq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestRunBackoff(t *testing.T) {
+	var b runBackoff
+	for i, want := range []time.Duration{ReconnectDelay, 2 * ReconnectDelay, 4 * ReconnectDelay} {
+		delay := b.next(time.Second)
+		if delay < want/2 || delay > want {
+			t.Errorf("attempt %d: delay %v out of range [%v, %v]", i+1, delay, want/2, want)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		if delay := b.next(0); delay > MaxReconnectDelay {
+			t.Fatalf("delay %v exceeds %v", delay, MaxReconnectDelay)
+		}
+	}
+
+	if delay := b.next(MinUptime); delay != 0 {
+		t.Errorf("delay after long uptime: %v", delay)
+	}
+	if delay := b.next(0); delay > ReconnectDelay {
+		t.Errorf("delay after reset: %v", delay)
+	}
+}
+
+func TestIsPermanentInitError(t *testing.T) {
+	tests := []struct {
+		err       error
+		permanent bool
+	}{
+		{&amqp091.Error{Code: amqp091.PreconditionFailed}, true},
+		{&amqp091.Error{Code: amqp091.AccessRefused}, true},
+		{&amqp091.Error{Code: amqp091.NotFound}, false},
+		{queueError("test", &amqp091.Error{Code: amqp091.PreconditionFailed}), true},
+		{ErrLazyQueueType, true},
+		{amqp091.ErrClosed, false},
+		{errors.New("other"), false},
+	}
+	for _, test := range tests {
+		if permanent := IsPermanentInitError(test.err); permanent != test.permanent {
+			t.Errorf("%v: got %v, want %v", test.err, permanent, test.permanent)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	var order []int
+	step := func(i int, err error) Initializer {
+		return func(*amqp091.Channel) error {
+			order = append(order, i)
+			return err
+		}
+	}
+
+	if err := Chain(step(1, nil), step(2, nil), step(3, nil))(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 3 || order[0] != 1 || order[1] != 2 || order[2] != 3 {
+		t.Errorf("unexpected order: %v", order)
+	}
+
+	order = nil
+	errTest := errors.New("test")
+	if err := Chain(step(1, nil), step(2, errTest), step(3, nil))(nil); err != errTest {
+		t.Errorf("got %v, want %v", err, errTest)
+	}
+	if len(order) != 2 {
+		t.Errorf("chain not interrupted: %v", order)
+	}
+}
+
+func TestAckHandlerPanic(t *testing.T) {
+	ack := new(ackRecorder)
+	handler := ackHandler(func(msg amqp091.Delivery) error {
+		if string(msg.Body) == "panic" {
+			panic("test")
+		}
+		return nil
+	})
+
+	handler(amqp091.Delivery{Acknowledger: ack, Body: []byte("ok")})
+	handler(amqp091.Delivery{Acknowledger: ack, Body: []byte("panic")})
+	if ack.acked != 1 || ack.nacked != 1 {
+		t.Errorf("acked %d, nacked %d", ack.acked, ack.nacked)
+	}
+}
+
+func TestWorkHandler(t *testing.T) {
+	calls := 0
+	handler, opts := workHandler(func(msg amqp091.Delivery) {
+		calls++
+		if string(msg.Body) == "panic" {
+			panic("test")
+		}
+	}, nil)
+	if !getConsumeOptions(opts).noAutoAck {
+		t.Error("manual acknowledgement not enabled")
+	}
+
+	ack := new(ackRecorder)
+	handler(amqp091.Delivery{Acknowledger: ack, Body: []byte("ok")})
+	handler(amqp091.Delivery{Acknowledger: ack, Body: []byte("panic")})
+	if calls != 2 || ack.acked != 1 || ack.nacked != 1 {
+		t.Errorf("calls %d, acked %d, nacked %d", calls, ack.acked, ack.nacked)
+	}
+
+	// при явно заданном подтверждении обработчик не меняется
+	_, opts = workHandler(func(amqp091.Delivery) {}, []ConsumeOption{WithAutoAck()})
+	if options := getConsumeOptions(opts); options.noAutoAck || !options.autoAck {
+		t.Error("explicit auto-ack overridden")
+	}
+}
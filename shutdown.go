@@ -0,0 +1,143 @@
+package rabbitmq
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// channelState хранит функции, вызываемые при плановом завершении работы, и учёт горутин обработчиков канала.
+type channelState struct {
+	hooks   []func()
+	workers sync.WaitGroup
+
+	consumers map[string]struct{} // имена обработчиков, получение сообщений которых отменяется при остановке
+
+	conn *amqp091.Connection // соединение, которому принадлежит канал
+}
+
+// channelStates хранит состояние каналов, инициализированных в Run.
+var channelStates = struct {
+	sync.Mutex
+	states map[*amqp091.Channel]*channelState
+}{states: make(map[*amqp091.Channel]*channelState)}
+
+// bindConnection регистрирует канал, инициализируемый в Run, и сохраняет соединение, которому он принадлежит.
+// Состояние хранится только для зарегистрированных каналов и удаляется releaseChannel; для остальных каналов
+// функции учёта ничего не сохраняют.
+func bindConnection(ch *amqp091.Channel, conn *amqp091.Connection) {
+	channelStates.Lock()
+	channelStates.states[ch] = &channelState{conn: conn}
+	channelStates.Unlock()
+}
+
+// channelConnection возвращает соединение, которому принадлежит канал, или nil, если канал инициализирован
+// не в Run. Используется обработчиками, которым для вспомогательных операций нужен отдельный канал.
+func channelConnection(ch *amqp091.Channel) *amqp091.Connection {
+	channelStates.Lock()
+	defer channelStates.Unlock()
+	if state, ok := channelStates.states[ch]; ok {
+		return state.conn
+	}
+	return nil
+}
+
+// onShutdown регистрирует функцию, вызываемую при плановом завершении работы для указанного канала,
+// пока соединение ещё не закрыто. Для канала, не зарегистрированного в Run, ничего не делает.
+func onShutdown(ch *amqp091.Channel, hook func()) {
+	channelStates.Lock()
+	if state, ok := channelStates.states[ch]; ok {
+		state.hooks = append(state.hooks, hook)
+	}
+	channelStates.Unlock()
+}
+
+// goWorker запускает функцию обработчика канала в отдельной горутине с учётом её завершения,
+// чтобы при плановой остановке Run мог дождаться окончания обработки всех сообщений. Для канала,
+// не зарегистрированного в Run, горутина запускается без учёта.
+func goWorker(ch *amqp091.Channel, worker func()) {
+	channelStates.Lock()
+	state, ok := channelStates.states[ch]
+	if ok {
+		state.workers.Add(1)
+	}
+	channelStates.Unlock()
+
+	go func() {
+		if ok {
+			defer state.workers.Done()
+		}
+		worker()
+	}()
+}
+
+// trackConsumer регистрирует обработчик канала, получение сообщений которого отменяется при плановом
+// завершении работы до закрытия соединения. Для канала, не зарегистрированного в Run, ничего не делает.
+func trackConsumer(ch *amqp091.Channel, tag string) {
+	channelStates.Lock()
+	if state, ok := channelStates.states[ch]; ok {
+		if state.consumers == nil {
+			state.consumers = make(map[string]struct{})
+		}
+		state.consumers[tag] = struct{}{}
+	}
+	channelStates.Unlock()
+}
+
+// shutdown вызывает зарегистрированные для канала функции в порядке регистрации, после чего отменяет
+// получение сообщений зарегистрированными обработчиками. Уже полученные сообщения передаются обработчикам,
+// и их приём можно подтвердить, пока соединение не закрыто.
+func shutdown(ch *amqp091.Channel) {
+	channelStates.Lock()
+	var hooks []func()
+	var consumers map[string]struct{}
+	if state, ok := channelStates.states[ch]; ok {
+		hooks, state.hooks = state.hooks, nil
+		consumers, state.consumers = state.consumers, nil
+	}
+	channelStates.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+	for tag := range consumers {
+		if err := ch.Cancel(tag, false); err != nil {
+			log.Debug().Err(err).Str("consumer", tag).Msg("cancel consumer")
+		}
+	}
+}
+
+// releaseChannel удаляет состояние канала и возвращает функцию для ожидания завершения его обработчиков.
+func releaseChannel(ch *amqp091.Channel) (wait func()) {
+	channelStates.Lock()
+	state, ok := channelStates.states[ch]
+	delete(channelStates.states, ch)
+	channelStates.Unlock()
+
+	if !ok {
+		return func() {}
+	}
+	return state.workers.Wait
+}
+
+// waitWorkers ожидает завершения обработчиков, но не дольше timeout. Возвращает false, если время ожидания
+// истекло.
+func waitWorkers(waits []func(), timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		for _, wait := range waits {
+			wait()
+		}
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
@@ -0,0 +1,92 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestChannelShutdown(t *testing.T) {
+	ch := new(amqp091.Channel)
+	bindConnection(ch, nil)
+	var hooks []int
+	onShutdown(ch, func() { hooks = append(hooks, 1) })
+	onShutdown(ch, func() { hooks = append(hooks, 2) })
+
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	goWorker(ch, func() {
+		<-release
+		close(finished)
+	})
+
+	shutdown(ch)
+	if len(hooks) != 2 || hooks[0] != 1 || hooks[1] != 2 {
+		t.Errorf("unexpected hooks: %v", hooks)
+	}
+
+	wait := releaseChannel(ch)
+	time.AfterFunc(10*time.Millisecond, func() { close(release) })
+	wait()
+	select {
+	case <-finished:
+	default:
+		t.Error("wait returned before worker finished")
+	}
+
+	shutdown(ch) // состояние канала уже удалено
+	if len(hooks) != 2 {
+		t.Errorf("hooks called after release: %v", hooks)
+	}
+}
+
+func TestWaitWorkers(t *testing.T) {
+	ch := new(amqp091.Channel)
+	bindConnection(ch, nil)
+	block := make(chan struct{})
+	goWorker(ch, func() { <-block })
+
+	waits := []func(){releaseChannel(ch)}
+	if waitWorkers(waits, 10*time.Millisecond) {
+		t.Error("wait finished while worker is blocked")
+	}
+
+	close(block)
+	if !waitWorkers(waits, time.Second) {
+		t.Error("wait timed out after worker finished")
+	}
+}
+
+func TestUnboundChannelState(t *testing.T) {
+	// каналы, не зарегистрированные в Run, не оставляют записей в общем состоянии
+	ch := new(amqp091.Channel)
+	onShutdown(ch, func() { t.Error("hook called for unbound channel") })
+	trackConsumer(ch, "consumer")
+	done := make(chan struct{})
+	goWorker(ch, func() { close(done) })
+	<-done
+
+	channelStates.Lock()
+	_, ok := channelStates.states[ch]
+	channelStates.Unlock()
+	if ok {
+		t.Error("state recorded for unbound channel")
+	}
+	shutdown(ch)
+}
+
+func TestChannelConnection(t *testing.T) {
+	ch, conn := new(amqp091.Channel), new(amqp091.Connection)
+	if channelConnection(ch) != nil {
+		t.Error("connection for unknown channel")
+	}
+	bindConnection(ch, conn)
+	if channelConnection(ch) != conn {
+		t.Error("connection not bound")
+	}
+	releaseChannel(ch)
+	if channelConnection(ch) != nil {
+		t.Error("connection kept after release")
+	}
+}
@@ -0,0 +1,30 @@
+package rabbitmq
+
+import (
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// WithStreamOffsetTime задаёт для stream очереди получение сообщений, начиная с указанного момента времени
+// (параметр обработчика x-stream-offset в виде временной метки). Время в будущем заменяется текущим.
+//
+// Параметр добавляется к уже заданным через WithArgs, поэтому опция должна указываться после неё.
+// Для получения сообщений из stream очереди также необходимо задать ограничение WithQOS и WithNoAutoAck.
+func WithStreamOffsetTime(t time.Time) ConsumeOption {
+	return newFuncConsumeOption(func(c *consumeOptions) {
+		offset := t
+		if now := time.Now(); offset.After(now) {
+			log.Warn().Time("offset", t).Msg("stream offset is in the future: using current time")
+			offset = now
+		}
+		log.Debug().Time("offset", offset).Msg("stream offset")
+
+		args := make(amqp091.Table, len(c.args)+1)
+		for k, v := range c.args {
+			args[k] = v
+		}
+		args["x-stream-offset"] = offset
+		c.args = args
+	})
+}
@@ -0,0 +1,28 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestStreamOffsetTime(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	args := amqp091.Table{"x-priority": 1}
+	options := getConsumeOptions([]ConsumeOption{WithArgs(args), WithStreamOffsetTime(past)})
+	if offset, _ := options.args["x-stream-offset"].(time.Time); !offset.Equal(past) {
+		t.Errorf("offset: got %v, want %v", options.args["x-stream-offset"], past)
+	}
+	if options.args["x-priority"] != 1 {
+		t.Error("consumer args lost")
+	}
+	if _, ok := args["x-stream-offset"]; ok {
+		t.Error("original args modified")
+	}
+
+	options = getConsumeOptions([]ConsumeOption{WithStreamOffsetTime(time.Now().Add(time.Hour))})
+	if offset, _ := options.args["x-stream-offset"].(time.Time); offset.After(time.Now()) {
+		t.Errorf("future offset not clamped: %v", offset)
+	}
+}
@@ -0,0 +1,53 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// ErrInvalidTable возвращается, если таблица параметров или заголовков содержит значения, не поддерживаемые
+// протоколом AMQP.
+var ErrInvalidTable = errors.New("invalid AMQP table")
+
+// Table преобразует произвольный словарь в таблицу AMQP и проверяет, что все значения поддерживаются протоколом:
+// строки, числа, bool, time.Time, []byte, а также вложенные таблицы и списки таких значений.
+// Вложенные словари map[string]any и списки []any преобразуются автоматически.
+// Для неподдерживаемых значений возвращается ошибка ErrInvalidTable с описанием поля.
+func Table(m map[string]any) (amqp091.Table, error) {
+	table, _ := tableValue(m).(amqp091.Table)
+	if err := validateTable(table); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// tableValue рекурсивно преобразует вложенные словари и списки в типы, используемые amqp091.
+func tableValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		table := make(amqp091.Table, len(v))
+		for k, item := range v {
+			table[k] = tableValue(item)
+		}
+		return table
+	case amqp091.Table:
+		return tableValue(map[string]any(v))
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = tableValue(item)
+		}
+		return list
+	}
+	return v
+}
+
+// validateTable проверяет, что все значения таблицы поддерживаются протоколом AMQP.
+func validateTable(table amqp091.Table) error {
+	if err := table.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTable, err)
+	}
+	return nil
+}
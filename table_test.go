@@ -0,0 +1,37 @@
+package rabbitmq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestTable(t *testing.T) {
+	table, err := Table(map[string]any{
+		"string": "value",
+		"int":    42,
+		"time":   time.Now(),
+		"bytes":  []byte("data"),
+		"nested": map[string]any{"list": []any{1, "two", map[string]any{"three": 3.0}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, ok := table["nested"].(amqp091.Table)
+	if !ok {
+		t.Fatalf("nested map not converted: %T", table["nested"])
+	}
+	if list, _ := nested["list"].([]any); len(list) != 3 {
+		t.Errorf("unexpected list: %v", nested["list"])
+	} else if _, ok := list[2].(amqp091.Table); !ok {
+		t.Errorf("map in list not converted: %T", list[2])
+	}
+
+	for _, value := range []any{struct{}{}, []string{"a"}, uint64(1), map[string]any{"bad": struct{}{}}} {
+		if _, err := Table(map[string]any{"field": value}); !errors.Is(err, ErrInvalidTable) {
+			t.Errorf("%T: got %v, want ErrInvalidTable", value, err)
+		}
+	}
+}